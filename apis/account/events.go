@@ -0,0 +1,17 @@
+package account
+
+// UserRegistered is written to the outbox in the transaction creating the user.
+type UserRegistered struct {
+	UserId int64  `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func (UserRegistered) EventName() string { return "account.user_registered" }
+
+// UserDeleted is written to the outbox in the transaction deleting the user.
+type UserDeleted struct {
+	UserId int64  `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func (UserDeleted) EventName() string { return "account.user_deleted" }
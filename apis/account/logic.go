@@ -1,5 +1,79 @@
 package account
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/zzztttkkk/0.0/internal/events"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
 func register() {
 
 }
+
+// createUser inserts the user and writes `UserRegistered` to the outbox in `tx`,
+// so that the event is relayed only if the user is created.
+func createUser(ctx context.Context, tx *sqlx.Tx, user *DBAccountUser) error {
+	if err := users.Insert(ctx, tx, user); err != nil {
+		return err
+	}
+	return events.Publish(ctx, tx, UserRegistered{UserId: user.Id, Email: user.Email})
+}
+
+// deleteUser soft deletes the user and writes `UserDeleted` to the outbox in `tx`.
+func deleteUser(ctx context.Context, tx *sqlx.Tx, user *DBAccountUser) error {
+	n, err := users.Delete(ctx, tx, user)
+	if err != nil || n < 1 {
+		return err
+	}
+	return events.Publish(ctx, tx, UserDeleted{UserId: user.Id, Email: user.Email})
+}
+
+// syncUser inserts or updates the user by email, `user.Id` and `user.Uuid` are filled back.
+func syncUser(ctx context.Context, exe sqlx.Executor, user *DBAccountUser) error {
+	_, err := sqlx.Upsert(ctx, exe, user, &sqlx.UpsertOptions{
+		ConflictColumns: []string{"email"},
+		UpdateColumns:   []string{"nickname", "avatar", "bio", "extpubinfo"},
+		Returning:       []string{"id", "uuid"},
+	})
+	return err
+}
+
+// listUsers returns users page by page, newest first.
+func listUsers(ctx context.Context, exe sqlx.Executor, cursor string, limit int) (*sqlx.KeysetPage[DBAccountUser], error) {
+	q := users.Query().PageQuery([]sqlx.SortKey{{Column: "created_at", Desc: true}, {Column: "id", Desc: true}}, limit)
+	return sqlx.Keyset[DBAccountUser](ctx, exe, q, cursor)
+}
+
+// searchUsers returns users matching `text` by nickname and bio, best first, with highlighted snippets.
+func searchUsers(ctx context.Context, exe sqlx.Executor, text string, limit int) ([]postgres.SearchResult[DBAccountUser], error) {
+	return postgres.Search(ctx, exe, users.Query().Limit(limit), text, &postgres.SearchOptions{
+		Headlines:       []string{"nickname", "bio"},
+		HeadlineOptions: "MaxWords=20, MinWords=5",
+	})
+}
+
+// setExtPubInfo sets keys of the public ext info of the user atomically, other keys are kept.
+func setExtPubInfo(ctx context.Context, exe sqlx.Executor, userId int64, values map[string]string) (int64, error) {
+	return updateExtPubInfo(ctx, exe, userId, postgres.HstoreSet("extpubinfo", "values"), sqlx.Params{"values": values})
+}
+
+// deleteExtPubInfo deletes keys of the public ext info of the user atomically.
+func deleteExtPubInfo(ctx context.Context, exe sqlx.Executor, userId int64, keys ...string) (int64, error) {
+	return updateExtPubInfo(ctx, exe, userId, postgres.HstoreDelete("extpubinfo", "keys"), sqlx.Params{"keys": keys})
+}
+
+func updateExtPubInfo(ctx context.Context, exe sqlx.Executor, userId int64, expr string, params sqlx.Params) (int64, error) {
+	params["id"] = userId
+	r, err := exe.Execute(
+		ctx,
+		fmt.Sprintf("UPDATE %s SET extpubinfo = %s WHERE id = ${id} AND deleted_at = 0", users.Table(), expr),
+		params,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
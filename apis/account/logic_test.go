@@ -0,0 +1,72 @@
+package account
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/zzztttkkk/0.0/internal/events"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/sqlite"
+	msqlite "modernc.org/sqlite"
+)
+
+func init() {
+	// `events.Publish` notifies the relays
+	msqlite.MustRegisterScalarFunction("pg_notify", 2, func(*msqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return nil, nil
+	})
+}
+
+func outboxNames(t *testing.T, db *sqlx.DB) []string {
+	names, err := sqlx.Many[string](context.Background(), db, "SELECT name FROM outbox ORDER BY id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestUserEvents(t *testing.T) {
+	ctx := context.Background()
+	db := sqlite.Open(":memory:", false, nil).DB
+	for _, model := range []any{DBAccountUser{}, events.Record{}} {
+		if err := db.CreateTable(ctx, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the event is discarded with the user
+	tx := db.MustBeginTx(ctx, nil)
+	if err := createUser(ctx, tx, &DBAccountUser{Email: "a@b.c", Nickname: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = tx.Rollback()
+	if names := outboxNames(t, db); len(names) != 0 {
+		t.Fatal(names)
+	}
+
+	user := &DBAccountUser{Email: "a@b.c", Nickname: "a"}
+	tx = db.MustBeginTx(ctx, nil)
+	if err := createUser(ctx, tx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx = db.MustBeginTx(ctx, nil)
+	if err := deleteUser(ctx, tx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := outboxNames(t, db)
+	if len(names) != 2 || names[0] != (UserRegistered{}).EventName() || names[1] != (UserDeleted{}).EventName() {
+		t.Fatal(names)
+	}
+	var payload string
+	if err := db.FetchOne(ctx, "SELECT payload FROM outbox WHERE id = 2", nil, &payload); err != nil || payload != `{"user_id":1,"email":"a@b.c"}` {
+		t.Fatal(payload, err)
+	}
+}
@@ -0,0 +1,50 @@
+package account
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zzztttkkk/0.0/apis/common"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
+// setupTable creates `account_user`, and upgrades the table created by older versions. Every step is idempotent,
+// so it runs at every startup.
+func setupTable(ctx context.Context, db *postgres.DB) error {
+	exists, err := sqlx.Scalar[bool](ctx, db.DB, "SELECT to_regclass(${table}) IS NOT NULL", sqlx.Params{"table": users.Table()})
+	if err != nil {
+		return err
+	}
+	if exists {
+		// `ADD COLUMN IF NOT EXISTS`, for tables created before optimistic locking and full text search.
+		// It runs before `CreateTable`, which creates the indexes of the columns, e.g. the gin index of `search`.
+		for _, column := range []string{"version", "search"} {
+			if err = db.AddColumn(ctx, DBAccountUser{}, column); err != nil {
+				return err
+			}
+		}
+	}
+	if err = db.CreateTable(ctx, DBAccountUser{}); err != nil {
+		return err
+	}
+	if err = common.MigrateBaseModel(ctx, db.DB, users.Table()); err != nil {
+		return err
+	}
+	// uniques of soft delete models are partial indexes of alive rows, created by `CreateTable` above,
+	// the column-level constraints of older tables would keep the values of deleted rows from being reused.
+	for _, column := range []string{"email", "uuid"} {
+		_, err := db.Execute(
+			ctx,
+			fmt.Sprintf(
+				"ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s",
+				postgres.Dialect{}.Quote(users.Table()), postgres.Dialect{}.Quote(users.Table()+"_"+column+"_key"),
+			),
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
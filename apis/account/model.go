@@ -1,10 +1,15 @@
 package account
 
 import (
+	"context"
+	"strings"
+
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/zzztttkkk/0.0/apis/common"
 	"github.com/zzztttkkk/0.0/config"
 	"github.com/zzztttkkk/0.0/internal"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
 )
 
 type DBAccountUser struct {
@@ -16,12 +21,35 @@ type DBAccountUser struct {
 	Avatar     *string        `db:"avatar;length=~120;nullable"`
 	Bio        *string        `db:"bio;length=~245;nullable"`
 	ExtPubInfo *pgtype.Hstore `db:"extpubinfo;nullable"`
+	Version    int64          `db:"version;version;default=0"`
+	Search     string         `db:"search;fts=nickname:A,bio:B"`
+}
+
+func (u *DBAccountUser) normalize() {
+	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+	u.Nickname = strings.TrimSpace(u.Nickname)
+}
+
+func (u *DBAccountUser) BeforeInsert(_ context.Context) error {
+	u.normalize()
+	return nil
+}
+
+func (u *DBAccountUser) BeforeUpdate(_ context.Context) error {
+	u.normalize()
+	return nil
 }
 
+var users = sqlx.NewRepo[DBAccountUser]()
+
 func init() {
 	internal.LazyInvoke(func(cfg *config.Config) {
 		db := cfg.DBMaster()
-		if err := db.CreateTable(cfg.Context(), DBAccountUser{}); err != nil {
+		// every instance runs this at startup, concurrent `CREATE TABLE IF NOT EXISTS` may conflict
+		err := db.WithLock(cfg.Context(), postgres.LockKey("0.0/ddl"), func(ctx context.Context) error {
+			return setupTable(ctx, db)
+		})
+		if err != nil {
 			panic(err)
 		}
 	})
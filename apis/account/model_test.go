@@ -0,0 +1,32 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/sqlite"
+)
+
+func TestSqliteModel(t *testing.T) {
+	ctx := context.Background()
+	db := sqlite.Open(":memory:", false, nil).DB
+	if err := db.CreateTable(ctx, DBAccountUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	v := "1"
+	bio := "a cat"
+	u := &DBAccountUser{Email: " A@b.c ", Nickname: "a", Bio: &bio, ExtPubInfo: &pgtype.Hstore{"k": &v}}
+	if err := users.Insert(ctx, db, u); err != nil {
+		t.Fatal(err)
+	}
+	got, err := users.Query().Where("email = ${email}", sqlx.Params{"email": "a@b.c"}).One(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Uuid.Valid || got.CreatedAt < 1 || got.Search != "a a cat" || got.ExtPubInfo == nil || *(*got.ExtPubInfo)["k"] != "1" {
+		t.Fatal(got)
+	}
+}
@@ -1,6 +1,44 @@
 package common
 
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
 type BaseModel struct {
 	CreatedAt int64 `db:"created_at;default=(extract(epoch from now()) * 1000)::bigint"`
-	DeletedAt int64 `db:"deleted_at;nullable"`
+	DeletedAt int64 `db:"deleted_at;default=0;softdelete"` // unix milliseconds, 0 means not deleted
+}
+
+// MigrateBaseModel backfills `deleted_at` of tables created when it was nullable, NULL meant not deleted,
+// which is 0 now. It does nothing once the column is not nullable, so it can run at every startup.
+func MigrateBaseModel(ctx context.Context, exe sqlx.Executor, table string) error {
+	nullable, err := sqlx.Scalar[string](
+		ctx, exe,
+		`SELECT is_nullable FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ${table} AND column_name = 'deleted_at'`,
+		sqlx.Params{"table": table},
+	)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && nullable != "YES") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	quoted := postgres.Dialect{}.Quote(table)
+	if _, err = exe.Execute(ctx, fmt.Sprintf("UPDATE %s SET deleted_at = 0 WHERE deleted_at IS NULL", quoted), nil); err != nil {
+		return err
+	}
+	_, err = exe.Execute(
+		ctx,
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN deleted_at SET DEFAULT 0, ALTER COLUMN deleted_at SET NOT NULL", quoted),
+		nil,
+	)
+	return err
 }
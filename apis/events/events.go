@@ -0,0 +1,32 @@
+package events
+
+import (
+	"context"
+
+	"github.com/zzztttkkk/0.0/config"
+	"github.com/zzztttkkk/0.0/internal"
+	"github.com/zzztttkkk/0.0/internal/events"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
+type AutoExport struct{}
+
+// The bus is provided as `*events.Bus`, other modules subscribe by
+// `internal.LazyInvoke(func(bus *events.Bus) { events.Subscribe(bus, name, handler) })`,
+// and publish by `events.Publish` in their transactions.
+func init() {
+	internal.LazyInvoke(func(cfg *config.Config) {
+		db := cfg.DBMaster()
+		bus := events.NewBus()
+		outbox := events.NewOutbox(db, bus, nil)
+		err := db.WithLock(cfg.Context(), postgres.LockKey("0.0/ddl"), func(ctx context.Context) error {
+			return outbox.CreateTable(ctx)
+		})
+		if err != nil {
+			panic(err)
+		}
+		internal.Provide(func() *events.Bus { return bus })
+		internal.Provide(func() *events.Outbox { return outbox })
+		internal.OnReady(func() { outbox.Start(cfg.Context()) })
+	})
+}
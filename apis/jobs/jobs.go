@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/zzztttkkk/0.0/config"
+	"github.com/zzztttkkk/0.0/internal"
+	"github.com/zzztttkkk/0.0/internal/queue"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
+type AutoExport struct{}
+
+// The queue is provided as `*queue.Queue`, other modules register handlers by
+// `internal.LazyInvoke(func(q *queue.Queue) { q.Handle(kind, handler) })`.
+func init() {
+	internal.LazyInvoke(func(cfg *config.Config) {
+		db := cfg.DBMaster()
+		q := queue.New(db, &queue.Options{
+			Workers:      cfg.Jobs.Workers,
+			PollInterval: time.Second * time.Duration(cfg.Jobs.PollInterval),
+		})
+		err := db.WithLock(cfg.Context(), postgres.LockKey("0.0/ddl"), func(ctx context.Context) error {
+			return q.CreateTable(ctx)
+		})
+		if err != nil {
+			panic(err)
+		}
+		internal.Provide(func() *queue.Queue { return q })
+		internal.OnReady(func() { q.Start(cfg.Context()) })
+	})
+}
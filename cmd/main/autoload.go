@@ -3,8 +3,12 @@ package main
 
 import (
 	_0 "github.com/zzztttkkk/0.0/apis/account"
+	_1 "github.com/zzztttkkk/0.0/apis/events"
+	_2 "github.com/zzztttkkk/0.0/apis/jobs"
 )
 
 var (
 	_ _0.AutoExport
+	_ _1.AutoExport
+	_ _2.AutoExport
 )
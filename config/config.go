@@ -26,6 +26,11 @@ type Config struct {
 		Data  string `toml:"data"`
 		Cache string `toml:"cache"`
 	} `toml:"redis"`
+
+	Jobs struct {
+		Workers      int `toml:"workers"`
+		PollInterval int `toml:"poll_interval"` // seconds
+	} `toml:"jobs"`
 }
 
 func (cfg *Config) Init(ctx context.Context) {
@@ -15,6 +15,7 @@ var (
 	lock     sync.Mutex
 	invokes  = make(map[uintptr]any)
 	provides = make(map[uintptr]any)
+	readies  []func()
 )
 
 func consume(k uintptr, v any, fn func(any) error, consumes *[]uintptr) {
@@ -75,6 +76,22 @@ func InvokeAll(timeout int) {
 			}
 		}
 	}
+
+	lock.Lock()
+	fns := readies
+	readies = nil
+	lock.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// OnReady registers `fn` to be called after `InvokeAll` is done, e.g. to start workers after handlers
+// are registered by other invokes.
+func OnReady(fn func()) {
+	lock.Lock()
+	defer lock.Unlock()
+	readies = append(readies, fn)
 }
 
 func LazyInvoke(fn any) {
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Event is published by services, the name identifies the type of it in the outbox, so it should not be changed.
+type Event interface {
+	EventName() string
+}
+
+type _Subscriber struct {
+	name   string
+	handle func(ctx context.Context, event Event) error
+}
+
+// Bus dispatches events to the subscribers of their names in process.
+type Bus struct {
+	lock        sync.RWMutex
+	subscribers map[string][]*_Subscriber
+	decoders    map[string]func(data []byte) (Event, error)
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[string][]*_Subscriber),
+		decoders:    make(map[string]func(data []byte) (Event, error)),
+	}
+}
+
+func nameOf[E Event]() string {
+	var e E
+	t := reflect.TypeOf(&e).Elem()
+	if t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(Event).EventName()
+	}
+	return e.EventName()
+}
+
+// Subscribe registers `handler` for events of type `E`, `name` is used in logs.
+func Subscribe[E Event](bus *Bus, name string, handler func(ctx context.Context, event E) error) {
+	ename := nameOf[E]()
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	if _, ok := bus.decoders[ename]; !ok {
+		bus.decoders[ename] = func(data []byte) (Event, error) {
+			var e E
+			if err := json.Unmarshal(data, &e); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	}
+	bus.subscribers[ename] = append(bus.subscribers[ename], &_Subscriber{
+		name: name,
+		handle: func(ctx context.Context, event Event) error {
+			e, ok := event.(E)
+			if !ok {
+				return fmt.Errorf("0.0/internal/events: `%s` expects %T, got %T", ename, e, event)
+			}
+			return handler(ctx, e)
+		},
+	})
+}
+
+// Publish calls the subscribers of `event` one by one, all of them are called even if some fail;
+// the first error is returned.
+func (bus *Bus) Publish(ctx context.Context, event Event) error {
+	return bus.dispatch(ctx, event.EventName(), event)
+}
+
+func (bus *Bus) dispatch(ctx context.Context, ename string, event Event) (err error) {
+	bus.lock.RLock()
+	subscribers := bus.subscribers[ename]
+	bus.lock.RUnlock()
+
+	for _, s := range subscribers {
+		if e := bus.call(ctx, s, event); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (bus *Bus) call(ctx context.Context, s *_Subscriber, event Event) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("0.0/internal/events: subscriber `%s` panic, %v", s.name, v)
+		}
+	}()
+	if err = s.handle(ctx, event); err != nil {
+		err = fmt.Errorf("0.0/internal/events: subscriber `%s` failed, %w", s.name, err)
+	}
+	return err
+}
+
+// decode returns nil if the event has no subscriber.
+func (bus *Bus) decode(ename string, data []byte) (Event, error) {
+	bus.lock.RLock()
+	decoder := bus.decoders[ename]
+	bus.lock.RUnlock()
+	if decoder == nil {
+		return nil, nil
+	}
+	return decoder(data)
+}
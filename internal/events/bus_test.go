@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type _Created struct {
+	Id int64 `json:"id"`
+}
+
+func (_Created) EventName() string { return "test.created" }
+
+type _Deleted struct {
+	Id int64 `json:"id"`
+}
+
+func (*_Deleted) EventName() string { return "test.deleted" }
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var created, deleted int64
+	Subscribe(bus, "created", func(_ context.Context, e _Created) error {
+		created += e.Id
+		return nil
+	})
+	Subscribe(bus, "failed", func(_ context.Context, e _Created) error { return errors.New("failed") })
+	Subscribe(bus, "deleted", func(_ context.Context, e *_Deleted) error {
+		deleted += e.Id
+		panic("deleted")
+	})
+
+	if err := bus.Publish(context.Background(), _Created{Id: 1}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := bus.Publish(context.Background(), &_Deleted{Id: 2}); err == nil {
+		t.Fatal("expected panic error")
+	}
+
+	event, err := bus.decode("test.created", []byte(`{"id":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = bus.dispatch(context.Background(), "test.created", event)
+	event, err = bus.decode("test.deleted", []byte(`{"id":4}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = bus.dispatch(context.Background(), "test.deleted", event)
+	if created != 4 || deleted != 6 {
+		t.Fatalf("created %d, deleted %d", created, deleted)
+	}
+
+	if event, err = bus.decode("test.unknown", []byte(`{}`)); event != nil || err != nil {
+		t.Fatal("expected no decoder")
+	}
+}
@@ -0,0 +1,271 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
+// NotifyChannel is notified when events are written to the outbox, inside a transaction it is delivered on commit.
+const NotifyChannel = "outbox"
+
+const nowMs = "(extract(epoch from now()) * 1000)::bigint"
+
+// Record is an event in the outbox, published records are kept for `Outbox.Replay` until they are pruned.
+type Record struct {
+	Id          int64  `db:"id;incr;primary;index=outbox_pending,asc,1"`
+	Name        string `db:"name;length=~64"`
+	Payload     []byte `db:"payload;sqltype=jsonb"`
+	Attempts    int32  `db:"attempts;default=0;index=outbox_pending,asc,0"`
+	LastError   string `db:"last_error;default=''"`
+	CreatedAt   int64  `db:"created_at;default=(extract(epoch from now()) * 1000)::bigint"`
+	PublishedAt int64  `db:"published_at;default=0"` // unix milliseconds, 0 means not published
+	LockedUntil int64  `db:"locked_until;default=0"` // unix milliseconds, the record is being relayed until then
+	FailedAt    int64  `db:"failed_at;default=0"`    // unix milliseconds, set after `MaxAttempts` failed relays
+}
+
+func (_ Record) TableName() string { return "outbox" }
+
+func (_ Record) IndexOptions() map[string]sqlx.IndexOptions {
+	return map[string]sqlx.IndexOptions{"outbox_pending": {Where: "published_at = 0 AND failed_at = 0"}}
+}
+
+// Publish writes `events` to the outbox by `exe`, they are relayed to the subscribers by `Outbox` at least once.
+// If `exe` is a `Tx`, they are relayed only after it commits, and discarded if it rolls back.
+func Publish(ctx context.Context, exe sqlx.Executor, events ...Event) error {
+	if len(events) < 1 {
+		return nil
+	}
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = exe.Execute(
+			ctx,
+			"INSERT INTO outbox (name, payload) VALUES (${name}, ${payload})",
+			sqlx.Params{"name": event.EventName(), "payload": data},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return postgres.Notify(ctx, exe, NotifyChannel, "")
+}
+
+type OutboxOptions struct {
+	BatchSize    int           // default 100
+	PollInterval time.Duration // default 1s
+	// Lease is how long records are locked by a relay, they are relayed again after the lease expires,
+	// e.g. the relay crashed. Default 1m.
+	Lease time.Duration
+	// MaxAttempts is how many times a record is relayed before it is dead-lettered, see `Outbox.Retry`. Default 10.
+	MaxAttempts int32
+}
+
+var ErrNoFailedRecord = errors.New("0.0/internal/events: no such failed record")
+
+// Outbox relays events from the outbox table to the bus. Records are leased before they are relayed, so multiple
+// instances can relay concurrently, and subscribers run outside of any transaction. A record whose subscribers fail
+// is retried on the next poll, the subscribers succeeded are called again, so they should be idempotent.
+// Records are relayed in order of ids, except that failed ones are retried after others.
+type Outbox struct {
+	db   *postgres.DB
+	bus  *Bus
+	opts OutboxOptions
+}
+
+func NewOutbox(db *postgres.DB, bus *Bus, opts *OutboxOptions) *Outbox {
+	o := &Outbox{db: db, bus: bus}
+	if opts != nil {
+		o.opts = *opts
+	}
+	if o.opts.BatchSize < 1 {
+		o.opts.BatchSize = 100
+	}
+	if o.opts.PollInterval <= 0 {
+		o.opts.PollInterval = time.Second
+	}
+	if o.opts.Lease <= 0 {
+		o.opts.Lease = time.Minute
+	}
+	if o.opts.MaxAttempts < 1 {
+		o.opts.MaxAttempts = 10
+	}
+	return o
+}
+
+// CreateTable creates the outbox table, and adds the columns missing in tables created by old versions.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	if err := o.db.CreateTable(ctx, Record{}); err != nil {
+		return err
+	}
+	for _, column := range []string{"locked_until", "failed_at"} {
+		if err := o.db.AddColumn(ctx, Record{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) dispatch(ctx context.Context, record *Record) error {
+	event, err := o.bus.decode(record.Name, record.Payload)
+	if err != nil || event == nil {
+		// no subscriber
+		return err
+	}
+	return o.bus.dispatch(ctx, record.Name, event)
+}
+
+var claimSQL = fmt.Sprintf(
+	`UPDATE outbox SET locked_until = %s + ${lease}
+WHERE id IN (
+	SELECT id FROM outbox
+	WHERE published_at = 0 AND failed_at = 0 AND locked_until < %s
+	ORDER BY attempts, id LIMIT ${limit}
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING *`,
+	nowMs, nowMs,
+)
+
+// RelayOnce leases a batch of unpublished records and relays them, it returns the count of records leased.
+func (o *Outbox) RelayOnce(ctx context.Context) (int, error) {
+	var records []*Record
+	err := o.db.FetchMany(ctx, claimSQL, sqlx.Params{"lease": o.opts.Lease.Milliseconds(), "limit": o.opts.BatchSize}, &records)
+	if err != nil {
+		return 0, err
+	}
+	// `RETURNING` is not ordered
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		return a.Attempts < b.Attempts || (a.Attempts == b.Attempts && a.Id < b.Id)
+	})
+
+	for _, record := range records {
+		if cause := o.dispatch(ctx, record); cause != nil {
+			err = o.fail(ctx, record, cause)
+		} else {
+			_, err = o.db.Execute(
+				ctx,
+				fmt.Sprintf("UPDATE outbox SET published_at = %s, locked_until = 0 WHERE id = ${id}", nowMs),
+				sqlx.Params{"id": record.Id},
+			)
+		}
+		if err != nil {
+			// the rest are relayed after their leases expire
+			return 0, err
+		}
+	}
+	return len(records), nil
+}
+
+// fail releases the record for the next relay, or dead-letters it after `MaxAttempts` failed relays.
+func (o *Outbox) fail(ctx context.Context, record *Record, cause error) error {
+	set := "attempts = attempts + 1, last_error = ${error}, locked_until = 0"
+	if record.Attempts+1 >= o.opts.MaxAttempts {
+		set += ", failed_at = " + nowMs
+		o.logf("0.0/internal/events: relay %d(%s) failed %d times, dead-lettered, %s", record.Id, record.Name, record.Attempts+1, cause)
+	} else {
+		o.logf("0.0/internal/events: relay %d(%s) failed, %s", record.Id, record.Name, cause)
+	}
+	_, err := o.db.Execute(
+		ctx,
+		fmt.Sprintf("UPDATE outbox SET %s WHERE id = ${id}", set),
+		sqlx.Params{"id": record.Id, "error": cause.Error()},
+	)
+	return err
+}
+
+// Retry makes a dead-lettered record relayed again, its attempts are reset.
+func (o *Outbox) Retry(ctx context.Context, id int64) error {
+	r, err := o.db.Execute(
+		ctx,
+		"UPDATE outbox SET attempts = 0, failed_at = 0, last_error = '' WHERE id = ${id} AND failed_at > 0",
+		sqlx.Params{"id": id},
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := r.RowsAffected(); n < 1 {
+		return ErrNoFailedRecord
+	}
+	return nil
+}
+
+// Replay dispatches the published records whose id is greater than `afterId` again, only the ones named in
+// `names` if it is not empty. It stops at the first failure, and returns the id of the last record replayed.
+func (o *Outbox) Replay(ctx context.Context, afterId int64, names ...string) (int64, error) {
+	var cond string
+	if len(names) > 0 {
+		cond = " AND name = ANY(${names})"
+	}
+	query := fmt.Sprintf("SELECT * FROM outbox WHERE id > ${after} AND published_at > 0%s ORDER BY id LIMIT ${limit}", cond)
+	for {
+		var records []*Record
+		err := o.db.FetchMany(ctx, query, sqlx.Params{"after": afterId, "names": names, "limit": o.opts.BatchSize}, &records)
+		if err != nil || len(records) < 1 {
+			return afterId, err
+		}
+		for _, record := range records {
+			if err = o.dispatch(ctx, record); err != nil {
+				return afterId, err
+			}
+			afterId = record.Id
+		}
+	}
+}
+
+// Prune deletes the records published before `before`.
+func (o *Outbox) Prune(ctx context.Context, before time.Time) (int64, error) {
+	r, err := o.db.Execute(
+		ctx,
+		"DELETE FROM outbox WHERE published_at > 0 AND published_at < ${before}",
+		sqlx.Params{"before": before.UnixMilli()},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
+
+func (o *Outbox) logf(format string, args ...any) {
+	if logger := o.db.Logger(); logger != nil {
+		logger.Printf(format, args...)
+	}
+}
+
+// Start relays records until `ctx` is done.
+func (o *Outbox) Start(ctx context.Context) {
+	var notifications <-chan *postgres.Notification
+	var err error
+	if notifications, err = o.db.Listen(ctx, NotifyChannel); err != nil {
+		o.logf("0.0/internal/events: listen error, outbox only polls, %s", err)
+	}
+
+	go func() {
+		for ctx.Err() == nil {
+			n, err := o.RelayOnce(ctx)
+			if err != nil {
+				o.logf("0.0/internal/events: relay error, %s", err)
+			} else if n >= o.opts.BatchSize {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+			case _, ok := <-notifications:
+				if !ok {
+					notifications = nil
+				}
+			case <-time.After(o.opts.PollInterval):
+			}
+		}
+	}()
+}
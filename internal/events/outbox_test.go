@@ -0,0 +1,378 @@
+package events
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
+// _OutboxServer simulates the outbox table of a postgres server, it runs the statements of the outbox by their shapes.
+type _OutboxServer struct {
+	sync.Mutex
+	now     int64 // unix milliseconds
+	records []*Record
+	// inTx is set while a subscriber runs if its connection is in a transaction
+	inTx bool
+}
+
+func (s *_OutboxServer) Connect(context.Context) (driver.Conn, error) {
+	return &_OutboxConn{server: s}, nil
+}
+
+func (s *_OutboxServer) Driver() driver.Driver { return nil }
+
+func (s *_OutboxServer) advance(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.now += d.Milliseconds()
+}
+
+func (s *_OutboxServer) record(id int64) Record {
+	s.Lock()
+	defer s.Unlock()
+	return *s.records[id-1]
+}
+
+func (s *_OutboxServer) find(id int64) *Record {
+	for _, record := range s.records {
+		if record.Id == id {
+			return record
+		}
+	}
+	return nil
+}
+
+type _OutboxConn struct {
+	server *_OutboxServer
+}
+
+// CheckNamedValue keeps the names of `Outbox.Replay`, which are bound as a postgres array.
+func (c *_OutboxConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.([]string); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+var _RecordColumns = []string{"id", "name", "payload", "attempts", "last_error", "created_at", "published_at", "locked_until", "failed_at"}
+
+func recordRows(records []*Record) *_OutboxRows {
+	rows := &_OutboxRows{columns: _RecordColumns}
+	for _, r := range records {
+		rows.values = append(rows.values, []driver.Value{
+			r.Id, r.Name, r.Payload, int64(r.Attempts), r.LastError, r.CreatedAt, r.PublishedAt, r.LockedUntil, r.FailedAt,
+		})
+	}
+	return rows
+}
+
+func (c *_OutboxConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s := c.server
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case strings.Contains(query, "FOR UPDATE SKIP LOCKED"):
+		var claimed []*Record
+		for _, r := range s.records {
+			if r.PublishedAt == 0 && r.FailedAt == 0 && r.LockedUntil < s.now {
+				claimed = append(claimed, r)
+			}
+		}
+		sort.Slice(claimed, func(i, j int) bool {
+			a, b := claimed[i], claimed[j]
+			return a.Attempts < b.Attempts || (a.Attempts == b.Attempts && a.Id < b.Id)
+		})
+		if limit := int(args[1].Value.(int64)); len(claimed) > limit {
+			claimed = claimed[:limit]
+		}
+		for _, r := range claimed {
+			r.LockedUntil = s.now + args[0].Value.(int64)
+		}
+		// `RETURNING` is not ordered
+		for i, j := 0, len(claimed)-1; i < j; i, j = i+1, j-1 {
+			claimed[i], claimed[j] = claimed[j], claimed[i]
+		}
+		return recordRows(claimed), nil
+	case strings.HasPrefix(query, "SELECT * FROM outbox WHERE id >"):
+		after, limit := args[0].Value.(int64), int(args[len(args)-1].Value.(int64))
+		var names map[string]bool
+		if len(args) == 3 {
+			names = map[string]bool{}
+			for _, name := range args[1].Value.([]string) {
+				names[name] = true
+			}
+		}
+		var records []*Record
+		for _, r := range s.records {
+			if r.Id > after && r.PublishedAt > 0 && (names == nil || names[r.Name]) && len(records) < limit {
+				records = append(records, r)
+			}
+		}
+		return recordRows(records), nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (c *_OutboxConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s := c.server
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO outbox"):
+		s.records = append(s.records, &Record{
+			Id: int64(len(s.records) + 1), Name: args[0].Value.(string), Payload: args[1].Value.([]byte), CreatedAt: s.now,
+		})
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "pg_notify("):
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE outbox SET published_at"):
+		r := s.find(args[0].Value.(int64))
+		r.PublishedAt, r.LockedUntil = s.now, 0
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE outbox SET attempts = attempts + 1"):
+		r := s.find(args[1].Value.(int64))
+		r.Attempts, r.LastError, r.LockedUntil = r.Attempts+1, args[0].Value.(string), 0
+		if strings.Contains(query, "failed_at") {
+			r.FailedAt = s.now
+		}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE outbox SET attempts = 0"):
+		if r := s.find(args[0].Value.(int64)); r != nil && r.FailedAt > 0 {
+			r.Attempts, r.FailedAt, r.LastError = 0, 0, ""
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "DELETE FROM outbox"):
+		before := args[0].Value.(int64)
+		var kept []*Record
+		for _, r := range s.records {
+			if r.PublishedAt == 0 || r.PublishedAt >= before {
+				kept = append(kept, r)
+			}
+		}
+		n := len(s.records) - len(kept)
+		s.records = kept
+		return driver.RowsAffected(n), nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (c *_OutboxConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+
+func (c *_OutboxConn) Close() error { return nil }
+
+// Begin is only used by publishing, the relay does not hold transactions.
+func (c *_OutboxConn) Begin() (driver.Tx, error) {
+	c.server.Lock()
+	defer c.server.Unlock()
+	c.server.inTx = true
+	return c, nil
+}
+
+func (c *_OutboxConn) Commit() error { return c.Rollback() }
+
+func (c *_OutboxConn) Rollback() error {
+	c.server.Lock()
+	defer c.server.Unlock()
+	c.server.inTx = false
+	return nil
+}
+
+type _OutboxRows struct {
+	columns []string
+	values  [][]driver.Value
+	idx     int
+}
+
+func (r *_OutboxRows) Columns() []string { return r.columns }
+
+func (r *_OutboxRows) Close() error { return nil }
+
+func (r *_OutboxRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.idx])
+	r.idx++
+	return nil
+}
+
+type _OutboxDriver struct {
+	postgres.Driver
+	server *_OutboxServer
+}
+
+func (d *_OutboxDriver) Open(string) (driver.Connector, error) { return d.server, nil }
+
+func newTestOutbox(bus *Bus, opts *OutboxOptions) (*Outbox, *_OutboxServer) {
+	server := &_OutboxServer{now: time.Now().UnixMilli()}
+	db := &postgres.DB{DB: sqlx.MustOpenDB(&_OutboxDriver{server: server}, "", false, nil)}
+	return NewOutbox(db, bus, opts), server
+}
+
+func TestRelayOnce(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus()
+	outbox, server := newTestOutbox(bus, &OutboxOptions{BatchSize: 2, MaxAttempts: 2})
+
+	var relayed []int64
+	var failing = map[int64]bool{2: true}
+	Subscribe(bus, "created", func(_ context.Context, e _Created) error {
+		if server.inTx {
+			t.Error("the subscriber runs in a transaction")
+		}
+		relayed = append(relayed, e.Id)
+		if failing[e.Id] {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	tx := outbox.db.MustBeginTx(ctx, nil)
+	if err := Publish(ctx, tx, _Created{Id: 1}, _Created{Id: 2}, _Created{Id: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// in order of ids, a batch at a time
+	if n, err := outbox.RelayOnce(ctx); err != nil || n != 2 {
+		t.Fatal(n, err)
+	}
+	if r := server.record(1); r.PublishedAt == 0 || r.LockedUntil != 0 {
+		t.Fatal(r)
+	}
+	if r := server.record(2); r.PublishedAt != 0 || r.Attempts != 1 || r.LastError == "" || r.FailedAt != 0 || r.LockedUntil != 0 {
+		t.Fatal(r)
+	}
+
+	// the failed one is retried after others, then dead-lettered
+	if n, err := outbox.RelayOnce(ctx); err != nil || n != 2 {
+		t.Fatal(n, err)
+	}
+	if r := server.record(2); r.Attempts != 2 || r.FailedAt == 0 {
+		t.Fatal(r)
+	}
+	if n, err := outbox.RelayOnce(ctx); err != nil || n != 0 {
+		t.Fatal(n, err)
+	}
+	if fmt.Sprint(relayed) != "[1 2 3 2]" {
+		t.Fatal(relayed)
+	}
+
+	// a dead-lettered record can be retried
+	if err := outbox.Retry(ctx, 1); err != ErrNoFailedRecord {
+		t.Fatal(err)
+	}
+	if err := outbox.Retry(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	failing[2] = false
+	if n, err := outbox.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	if r := server.record(2); r.PublishedAt == 0 || r.Attempts != 0 {
+		t.Fatal(r)
+	}
+}
+
+func TestRelayLease(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus()
+	outbox, server := newTestOutbox(bus, &OutboxOptions{Lease: time.Minute})
+	if err := Publish(ctx, outbox.db.DB, _Created{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a relay crashed after leasing the record
+	var records []*Record
+	if err := outbox.db.FetchMany(ctx, claimSQL, sqlx.Params{"lease": time.Minute.Milliseconds(), "limit": 10}, &records); err != nil || len(records) != 1 {
+		t.Fatal(records, err)
+	}
+	if n, err := outbox.RelayOnce(ctx); err != nil || n != 0 {
+		t.Fatal(n, err)
+	}
+	server.advance(time.Minute + time.Millisecond)
+	if n, err := outbox.RelayOnce(ctx); err != nil || n != 1 || server.record(1).PublishedAt == 0 {
+		t.Fatal(n, err)
+	}
+}
+
+func TestReplayAndPrune(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus()
+	outbox, server := newTestOutbox(bus, &OutboxOptions{BatchSize: 2})
+
+	var created, deleted []int64
+	var failing int64
+	Subscribe(bus, "created", func(_ context.Context, e _Created) error {
+		created = append(created, e.Id)
+		return nil
+	})
+	Subscribe(bus, "deleted", func(_ context.Context, e *_Deleted) error {
+		deleted = append(deleted, e.Id)
+		if e.Id == failing {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	if err := Publish(ctx, outbox.db.DB, _Created{Id: 1}, &_Deleted{Id: 2}, _Created{Id: 3}, _Created{Id: 4}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := outbox.RelayOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created, deleted = nil, nil
+
+	// all batches, or only the named ones
+	if last, err := outbox.Replay(ctx, 1); err != nil || last != 4 || fmt.Sprint(created, deleted) != "[3 4] [2]" {
+		t.Fatal(last, err, created, deleted)
+	}
+	created, deleted = nil, nil
+	if last, err := outbox.Replay(ctx, 0, (&_Deleted{}).EventName()); err != nil || last != 2 || len(created) != 0 || fmt.Sprint(deleted) != "[2]" {
+		t.Fatal(last, err, created, deleted)
+	}
+
+	// unpublished records are not replayed
+	server.advance(time.Second)
+	if err := Publish(ctx, outbox.db.DB, &_Deleted{Id: 5}, _Created{Id: 6}); err != nil {
+		t.Fatal(err)
+	}
+	created, deleted = nil, nil
+	if last, err := outbox.Replay(ctx, 4); err != nil || last != 4 || len(created)+len(deleted) != 0 {
+		t.Fatal(last, err)
+	}
+	if _, err := outbox.RelayOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// stops at the first failure
+	failing = 5
+	created, deleted = nil, nil
+	if last, err := outbox.Replay(ctx, 0); err == nil || last != 4 || fmt.Sprint(created, deleted) != "[1 3 4] [2 5]" {
+		t.Fatal(last, err, created, deleted)
+	}
+
+	// only the records published before are pruned
+	if n, err := outbox.Prune(ctx, time.UnixMilli(server.now)); err != nil || n != 4 {
+		t.Fatal(n, err)
+	}
+	if len(server.records) != 2 || server.records[0].Id != 5 {
+		t.Fatal(server.records)
+	}
+}
@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type Status string
+
+const (
+	StatusPending = Status("pending")
+	StatusRunning = Status("running")
+	StatusDone    = Status("done")
+	StatusDead    = Status("dead") // failed `MaxAttempts` times, see `Queue.Retry`
+)
+
+// nowMs is the current unix milliseconds of the database, times of jobs are unix milliseconds like `common.BaseModel`.
+const nowMs = "(extract(epoch from now()) * 1000)::bigint"
+
+type Job struct {
+	Id          int64          `db:"id;incr;primary"`
+	Kind        string         `db:"kind;length=~64"`
+	Payload     []byte         `db:"payload;sqltype=jsonb"`
+	Status      Status         `db:"status;length=~16;default='pending';index=jobs_fetch,asc,0"`
+	RunAt       int64          `db:"run_at;index=jobs_fetch,asc,1"`
+	Attempts    int32          `db:"attempts;default=0"`
+	MaxAttempts int32          `db:"max_attempts"`
+	LockedUntil int64          `db:"locked_until;default=0"`
+	UniqueKey   sql.NullString `db:"unique_key;length=~128;index=jobs_unique_key_unique,asc"`
+	LastError   string         `db:"last_error;default=''"`
+	CreatedAt   int64          `db:"created_at;default=(extract(epoch from now()) * 1000)::bigint"`
+	UpdatedAt   int64          `db:"updated_at;default=(extract(epoch from now()) * 1000)::bigint"`
+}
+
+func (_ Job) TableName() string { return "jobs" }
+
+// IndexOptions makes the unique key unique among unfinished jobs only.
+func (_ Job) IndexOptions() map[string]sqlx.IndexOptions {
+	return map[string]sqlx.IndexOptions{
+		"jobs_unique_key_unique": {Where: "unique_key IS NOT NULL AND status IN ('pending', 'running')"},
+	}
+}
+
+// Decode unmarshals the json payload into `v`.
+func (job *Job) Decode(v any) error { return json.Unmarshal(job.Payload, v) }
@@ -0,0 +1,331 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
+// NotifyChannel is notified with the job kind on every enqueue, idle workers are woken by it.
+const NotifyChannel = "jobs"
+
+var (
+	ErrDuplicateJob = errors.New("0.0/internal/queue: an unfinished job with the same unique key exists")
+	ErrNoDeadJob    = errors.New("0.0/internal/queue: no such dead job")
+)
+
+// Handler runs a job, the job is retried if it returns an error or panics.
+// `ctx` is done when the lease of the job expires.
+type Handler func(ctx context.Context, job *Job) error
+
+type EnqueueOptions struct {
+	RunAt       time.Time // zero means now
+	MaxAttempts int32     // `DefaultMaxAttempts` if less than 1
+	// UniqueKey makes the enqueue fail with `ErrDuplicateJob` while another job with the same key is pending or running.
+	UniqueKey string
+}
+
+const DefaultMaxAttempts = 5
+
+// Enqueue inserts a job, `payload` is marshaled as json unless it is already `[]byte` or `json.RawMessage`.
+// If `exe` is a `Tx`, the job is visible to workers only after the transaction commits.
+func Enqueue(ctx context.Context, exe sqlx.Executor, kind string, payload any, opts *EnqueueOptions) (int64, error) {
+	if opts == nil {
+		opts = &EnqueueOptions{}
+	}
+
+	var data []byte
+	switch v := payload.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return 0, err
+		}
+	}
+
+	params := sqlx.Params{"kind": kind, "payload": data, "max_attempts": opts.MaxAttempts}
+	if opts.MaxAttempts < 1 {
+		params["max_attempts"] = int32(DefaultMaxAttempts)
+	}
+	runAt := nowMs
+	if !opts.RunAt.IsZero() {
+		runAt = "${run_at}"
+		params["run_at"] = opts.RunAt.UnixMilli()
+	}
+	var uniqueKey sql.NullString
+	if len(opts.UniqueKey) > 0 {
+		uniqueKey = sql.NullString{String: opts.UniqueKey, Valid: true}
+	}
+	params["unique_key"] = uniqueKey
+
+	var id int64
+	err := exe.FetchOne(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO jobs (kind, payload, run_at, max_attempts, unique_key)
+VALUES (${kind}, ${payload}, %s, ${max_attempts}, ${unique_key})
+ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+RETURNING id`,
+			runAt,
+		),
+		params,
+		&id,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrDuplicateJob
+		}
+		return 0, err
+	}
+	if err = postgres.Notify(ctx, exe, NotifyChannel, kind); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+type Options struct {
+	Workers      int           // default 4
+	PollInterval time.Duration // default 1s, workers also poll when they are not woken by notifications
+	// Lease is how long a job is locked by a worker, it is fetched again after the lease expires,
+	// e.g. the worker crashed. Default 5m.
+	Lease time.Duration
+	// Backoff is the retry delay after the first failed attempt, doubled after each attempt up to MaxBackoff.
+	// Default 10s and 1h.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type Queue struct {
+	db   *postgres.DB
+	opts Options
+
+	lock     sync.RWMutex
+	handlers map[string]Handler
+}
+
+func New(db *postgres.DB, opts *Options) *Queue {
+	q := &Queue{db: db, handlers: make(map[string]Handler)}
+	if opts != nil {
+		q.opts = *opts
+	}
+	if q.opts.Workers < 1 {
+		q.opts.Workers = 4
+	}
+	if q.opts.PollInterval <= 0 {
+		q.opts.PollInterval = time.Second
+	}
+	if q.opts.Lease <= 0 {
+		q.opts.Lease = time.Minute * 5
+	}
+	if q.opts.Backoff <= 0 {
+		q.opts.Backoff = time.Second * 10
+	}
+	if q.opts.MaxBackoff < q.opts.Backoff {
+		q.opts.MaxBackoff = time.Hour
+	}
+	return q
+}
+
+func (q *Queue) CreateTable(ctx context.Context) error { return q.db.CreateTable(ctx, Job{}) }
+
+// Handle registers the handler of `kind`, workers only fetch jobs whose kinds are registered.
+func (q *Queue) Handle(kind string, handler Handler) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if _, ok := q.handlers[kind]; ok {
+		panic(fmt.Errorf("0.0/internal/queue: duplicate handler of `%s`", kind))
+	}
+	q.handlers[kind] = handler
+}
+
+func (q *Queue) kinds() []string {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	var kinds []string
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (q *Queue) handler(kind string) Handler {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.handlers[kind]
+}
+
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts *EnqueueOptions) (int64, error) {
+	return Enqueue(ctx, q.db.DB, kind, payload, opts)
+}
+
+// Retry moves a dead job back to pending, its attempts are reset.
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	r, err := q.db.Execute(
+		ctx,
+		fmt.Sprintf(
+			"UPDATE jobs SET status = 'pending', attempts = 0, run_at = %s, last_error = '', updated_at = %s WHERE id = ${id} AND status = 'dead'",
+			nowMs, nowMs,
+		),
+		sqlx.Params{"id": id},
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := r.RowsAffected(); n < 1 {
+		return ErrNoDeadJob
+	}
+	return nil
+}
+
+// Backoff returns the retry delay after `attempts` failed attempts.
+func (q *Queue) Backoff(attempts int32) time.Duration {
+	d := q.opts.Backoff
+	for i := int32(1); i < attempts && d < q.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > q.opts.MaxBackoff {
+		d = q.opts.MaxBackoff
+	}
+	return d
+}
+
+var fetchSQL = fmt.Sprintf(
+	`UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_until = %s + ${lease}, updated_at = %s
+WHERE id = (
+	SELECT id FROM jobs
+	WHERE kind = ANY(${kinds}) AND run_at <= %s AND (status = 'pending' OR (status = 'running' AND locked_until < %s))
+	ORDER BY run_at, id LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING *`,
+	nowMs, nowMs, nowMs, nowMs,
+)
+
+func (q *Queue) fetch(ctx context.Context, kinds []string) (*Job, error) {
+	var job Job
+	err := q.db.FetchOne(ctx, fetchSQL, sqlx.Params{"lease": q.opts.Lease.Milliseconds(), "kinds": kinds}, &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (q *Queue) call(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("0.0/internal/queue: handler panic, %v", v)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, q.opts.Lease)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// finish updates the job if it is still leased by this attempt.
+func (q *Queue) finish(ctx context.Context, job *Job, cause error) error {
+	params := sqlx.Params{"id": job.Id, "attempts": job.Attempts}
+	var set string
+	switch {
+	case cause == nil:
+		set = "status = 'done', last_error = ''"
+	case job.Attempts >= job.MaxAttempts:
+		set = "status = 'dead', last_error = ${error}"
+		params["error"] = cause.Error()
+	default:
+		set = fmt.Sprintf("status = 'pending', run_at = %s + ${delay}, last_error = ${error}", nowMs)
+		params["delay"] = q.Backoff(job.Attempts).Milliseconds()
+		params["error"] = cause.Error()
+	}
+	_, err := q.db.Execute(
+		ctx,
+		fmt.Sprintf(
+			"UPDATE jobs SET %s, locked_until = 0, updated_at = %s WHERE id = ${id} AND attempts = ${attempts} AND status = 'running'",
+			set, nowMs,
+		),
+		params,
+	)
+	return err
+}
+
+// RunOnce fetches and runs one job, it returns false if there is no job to run.
+func (q *Queue) RunOnce(ctx context.Context) (bool, error) {
+	kinds := q.kinds()
+	if len(kinds) < 1 {
+		return false, nil
+	}
+	job, err := q.fetch(ctx, kinds)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var cause error
+	if job.Attempts > job.MaxAttempts {
+		// the lease of the last attempt expired
+		cause = fmt.Errorf("0.0/internal/queue: lease expired, %s", job.LastError)
+	} else {
+		cause = q.call(ctx, q.handler(job.Kind), job)
+	}
+	if cause != nil {
+		q.logf("0.0/internal/queue: job %d(%s) attempt %d/%d failed, %s", job.Id, job.Kind, job.Attempts, job.MaxAttempts, cause)
+	}
+	return true, q.finish(ctx, job, cause)
+}
+
+func (q *Queue) logf(format string, args ...any) {
+	if logger := q.db.Logger(); logger != nil {
+		logger.Printf(format, args...)
+	}
+}
+
+// Start starts the workers, they stop when `ctx` is done.
+func (q *Queue) Start(ctx context.Context) {
+	wake := make(chan struct{}, q.opts.Workers)
+	if notifications, err := q.db.Listen(ctx, NotifyChannel); err != nil {
+		q.logf("0.0/internal/queue: listen error, workers only poll, %s", err)
+	} else {
+		go func() {
+			for range notifications {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+	for i := 0; i < q.opts.Workers; i++ {
+		go q.work(ctx, wake)
+	}
+}
+
+func (q *Queue) work(ctx context.Context, wake <-chan struct{}) {
+	for ctx.Err() == nil {
+		ran, err := q.RunOnce(ctx)
+		if err != nil {
+			q.logf("0.0/internal/queue: run error, %s", err)
+		} else if ran {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-time.After(q.opts.PollInterval):
+		}
+	}
+}
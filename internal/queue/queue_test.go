@@ -0,0 +1,400 @@
+package queue
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+)
+
+func TestBackoff(t *testing.T) {
+	q := New(nil, &Options{Backoff: time.Second, MaxBackoff: time.Second * 10})
+	for attempts, expected := range []time.Duration{1: time.Second, 2: time.Second * 2, 3: time.Second * 4, 4: time.Second * 8, 5: time.Second * 10, 9: time.Second * 10} {
+		if attempts > 0 && expected > 0 && q.Backoff(int32(attempts)) != expected {
+			t.Fatalf("attempts %d, expected %s, got %s", attempts, expected, q.Backoff(int32(attempts)))
+		}
+	}
+}
+
+// _JobServer simulates the jobs table of a postgres server, it runs the statements of the queue by their shapes.
+// Every statement runs under the lock, like a row locked by `FOR UPDATE SKIP LOCKED` is skipped by others.
+type _JobServer struct {
+	sync.Mutex
+	now      int64 // unix milliseconds
+	jobs     []*Job
+	notified []string
+}
+
+func (s *_JobServer) Connect(context.Context) (driver.Conn, error) { return &_JobConn{server: s}, nil }
+
+func (s *_JobServer) Driver() driver.Driver { return nil }
+
+func (s *_JobServer) advance(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.now += d.Milliseconds()
+}
+
+func (s *_JobServer) job(id int64) Job {
+	s.Lock()
+	defer s.Unlock()
+	return *s.jobs[id-1]
+}
+
+type _JobConn struct {
+	server *_JobServer
+}
+
+// CheckNamedValue keeps the kinds of jobs to fetch, which are bound as a postgres array.
+func (c *_JobConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.([]string); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+var _JobColumns = []string{
+	"id", "kind", "payload", "status", "run_at", "attempts", "max_attempts",
+	"locked_until", "unique_key", "last_error", "created_at", "updated_at",
+}
+
+func jobValues(job *Job) []driver.Value {
+	var uniqueKey driver.Value
+	if job.UniqueKey.Valid {
+		uniqueKey = job.UniqueKey.String
+	}
+	return []driver.Value{
+		job.Id, job.Kind, job.Payload, string(job.Status), job.RunAt, int64(job.Attempts), int64(job.MaxAttempts),
+		job.LockedUntil, uniqueKey, job.LastError, job.CreatedAt, job.UpdatedAt,
+	}
+}
+
+func (c *_JobConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s := c.server
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO jobs"):
+		job := &Job{Id: int64(len(s.jobs) + 1), Kind: args[0].Value.(string), Payload: args[1].Value.([]byte), Status: StatusPending, RunAt: s.now}
+		if len(args) == 5 {
+			job.RunAt = args[2].Value.(int64)
+			args = append(args[:2], args[3:]...)
+		}
+		job.MaxAttempts = int32(args[2].Value.(int64))
+		if key, ok := args[3].Value.(string); ok {
+			job.UniqueKey.String, job.UniqueKey.Valid = key, true
+			for _, v := range s.jobs {
+				if v.UniqueKey == job.UniqueKey && (v.Status == StatusPending || v.Status == StatusRunning) {
+					return &_JobRows{columns: []string{"id"}}, nil
+				}
+			}
+		}
+		job.CreatedAt, job.UpdatedAt = s.now, s.now
+		s.jobs = append(s.jobs, job)
+		return &_JobRows{columns: []string{"id"}, values: [][]driver.Value{{job.Id}}}, nil
+	case strings.Contains(query, "FOR UPDATE SKIP LOCKED"):
+		kinds := map[string]bool{}
+		for _, kind := range args[1].Value.([]string) {
+			kinds[kind] = true
+		}
+		var candidates []*Job
+		for _, job := range s.jobs {
+			leased := job.Status == StatusRunning && job.LockedUntil >= s.now
+			if kinds[job.Kind] && job.RunAt <= s.now && (job.Status == StatusPending || (job.Status == StatusRunning && !leased)) {
+				candidates = append(candidates, job)
+			}
+		}
+		if len(candidates) < 1 {
+			return &_JobRows{columns: _JobColumns}, nil
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			return a.RunAt < b.RunAt || (a.RunAt == b.RunAt && a.Id < b.Id)
+		})
+		job := candidates[0]
+		job.Status, job.Attempts, job.LockedUntil, job.UpdatedAt = StatusRunning, job.Attempts+1, s.now+args[0].Value.(int64), s.now
+		return &_JobRows{columns: _JobColumns, values: [][]driver.Value{jobValues(job)}}, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (c *_JobConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s := c.server
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case strings.Contains(query, "pg_notify("):
+		s.notified = append(s.notified, args[0].Value.(string)+":"+args[1].Value.(string))
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE jobs SET status = 'pending', attempts = 0,"):
+		// Retry
+		for _, job := range s.jobs {
+			if job.Id == args[0].Value.(int64) && job.Status == StatusDead {
+				job.Status, job.Attempts, job.RunAt, job.LastError, job.UpdatedAt = StatusPending, 0, s.now, "", s.now
+				return driver.RowsAffected(1), nil
+			}
+		}
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "UPDATE jobs SET"):
+		// finish, `id` and `attempts` are the last params
+		id, attempts := args[len(args)-2].Value.(int64), int32(args[len(args)-1].Value.(int64))
+		for _, job := range s.jobs {
+			if job.Id != id || job.Attempts != attempts || job.Status != StatusRunning {
+				continue
+			}
+			switch {
+			case strings.Contains(query, "status = 'done'"):
+				job.Status, job.LastError = StatusDone, ""
+			case strings.Contains(query, "status = 'dead'"):
+				job.Status, job.LastError = StatusDead, args[0].Value.(string)
+			default:
+				job.Status, job.RunAt, job.LastError = StatusPending, s.now+args[0].Value.(int64), args[1].Value.(string)
+			}
+			job.LockedUntil, job.UpdatedAt = 0, s.now
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (c *_JobConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+
+func (c *_JobConn) Close() error { return nil }
+
+func (c *_JobConn) Begin() (driver.Tx, error) { return nil, errors.New("unexpected transaction") }
+
+type _JobRows struct {
+	columns []string
+	values  [][]driver.Value
+	idx     int
+}
+
+func (r *_JobRows) Columns() []string { return r.columns }
+
+func (r *_JobRows) Close() error { return nil }
+
+func (r *_JobRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.idx])
+	r.idx++
+	return nil
+}
+
+type _JobDriver struct {
+	postgres.Driver
+	server *_JobServer
+}
+
+func (d *_JobDriver) Open(string) (driver.Connector, error) { return d.server, nil }
+
+func newTestQueue(opts *Options) (*Queue, *_JobServer) {
+	server := &_JobServer{now: time.Now().UnixMilli()}
+	db := &postgres.DB{DB: sqlx.MustOpenDB(&_JobDriver{server: server}, "", false, nil)}
+	return New(db, opts), server
+}
+
+func TestEnqueue(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestQueue(nil)
+
+	id, err := q.Enqueue(ctx, "email", map[string]string{"to": "a@b.c"}, &EnqueueOptions{UniqueKey: "verify:1"})
+	if err != nil || id != 1 {
+		t.Fatal(id, err)
+	}
+	job := server.job(id)
+	var payload map[string]string
+	if err = job.Decode(&payload); err != nil || payload["to"] != "a@b.c" || job.MaxAttempts != DefaultMaxAttempts {
+		t.Fatal(job, err)
+	}
+	if strings.Join(server.notified, ",") != NotifyChannel+":email" {
+		t.Fatal(server.notified)
+	}
+
+	// an unfinished job with the same key exists
+	if id, err = q.Enqueue(ctx, "email", nil, &EnqueueOptions{UniqueKey: "verify:1"}); err != ErrDuplicateJob || id != 0 {
+		t.Fatal(id, err)
+	}
+	if len(server.notified) != 1 {
+		t.Fatal(server.notified)
+	}
+
+	// the key can be reused after the job is done
+	q.Handle("email", func(context.Context, *Job) error { return nil })
+	if ran, err := q.RunOnce(ctx); err != nil || !ran || server.job(1).Status != StatusDone {
+		t.Fatal(ran, err)
+	}
+	if id, err = q.Enqueue(ctx, "email", nil, &EnqueueOptions{UniqueKey: "verify:1"}); err != nil || id != 2 {
+		t.Fatal(id, err)
+	}
+
+	// scheduled jobs are not fetched before `RunAt`
+	runAt := time.UnixMilli(server.now).Add(time.Minute)
+	if id, err = q.Enqueue(ctx, "email", nil, &EnqueueOptions{RunAt: runAt}); err != nil || server.job(id).RunAt != runAt.UnixMilli() {
+		t.Fatal(id, err)
+	}
+	if ran, err := q.RunOnce(ctx); err != nil || !ran || server.job(2).Status != StatusDone {
+		t.Fatal(ran, err)
+	}
+	if ran, err := q.RunOnce(ctx); err != nil || ran {
+		t.Fatal(ran, err)
+	}
+	server.advance(time.Minute)
+	if ran, err := q.RunOnce(ctx); err != nil || !ran || server.job(3).Status != StatusDone {
+		t.Fatal(ran, err)
+	}
+}
+
+func TestRunOnceSkipLocked(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestQueue(nil)
+	for i := 0; i < 2; i++ {
+		if _, err := q.Enqueue(ctx, "a", i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// both handlers run at the same time, so the job locked by one is skipped by the other
+	var started sync.WaitGroup
+	started.Add(2)
+	var lock sync.Mutex
+	runs := map[int64]int{}
+	q.Handle("a", func(_ context.Context, job *Job) error {
+		lock.Lock()
+		runs[job.Id]++
+		lock.Unlock()
+		started.Done()
+		started.Wait()
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ran, err := q.RunOnce(ctx); err != nil || !ran {
+				errs <- fmt.Errorf("ran: %t, err: %v", ran, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[1] != 1 || runs[2] != 1 {
+		t.Fatal(runs)
+	}
+	for _, id := range []int64{1, 2} {
+		if job := server.job(id); job.Status != StatusDone || job.Attempts != 1 {
+			t.Fatal(job)
+		}
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestQueue(&Options{Lease: time.Minute})
+	q.Handle("a", func(context.Context, *Job) error { return nil })
+	if _, err := q.Enqueue(ctx, "a", nil, &EnqueueOptions{MaxAttempts: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a worker crashed after fetching the job, it is not fetched again until the lease expires
+	if _, err := q.fetch(ctx, q.kinds()); err != nil {
+		t.Fatal(err)
+	}
+	if ran, err := q.RunOnce(ctx); err != nil || ran {
+		t.Fatal(ran, err)
+	}
+	server.advance(time.Minute + time.Millisecond)
+	if ran, err := q.RunOnce(ctx); err != nil || !ran {
+		t.Fatal(ran, err)
+	}
+	if job := server.job(1); job.Status != StatusDone || job.Attempts != 2 {
+		t.Fatal(job)
+	}
+
+	// the lease of the last attempt expired
+	if _, err := q.Enqueue(ctx, "a", nil, &EnqueueOptions{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.fetch(ctx, q.kinds()); err != nil {
+		t.Fatal(err)
+	}
+	server.advance(time.Minute + time.Millisecond)
+	if ran, err := q.RunOnce(ctx); err != nil || !ran {
+		t.Fatal(ran, err)
+	}
+	if job := server.job(2); job.Status != StatusDead || !strings.Contains(job.LastError, "lease expired") {
+		t.Fatal(job)
+	}
+}
+
+func TestDeadAndRetry(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestQueue(&Options{Backoff: time.Second})
+	var fail = true
+	q.Handle("a", func(context.Context, *Job) error {
+		if fail {
+			panic("boom")
+		}
+		return nil
+	})
+	if _, err := q.Enqueue(ctx, "a", nil, &EnqueueOptions{MaxAttempts: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// retried after the backoff
+	if ran, err := q.RunOnce(ctx); err != nil || !ran {
+		t.Fatal(ran, err)
+	}
+	if job := server.job(1); job.Status != StatusPending || job.RunAt != server.now+1000 || !strings.Contains(job.LastError, "boom") {
+		t.Fatal(job)
+	}
+	if ran, err := q.RunOnce(ctx); err != nil || ran {
+		t.Fatal(ran, err)
+	}
+	server.advance(time.Second)
+
+	// dead after `MaxAttempts` failed attempts
+	if ran, err := q.RunOnce(ctx); err != nil || !ran {
+		t.Fatal(ran, err)
+	}
+	if job := server.job(1); job.Status != StatusDead || job.Attempts != 2 {
+		t.Fatal(job)
+	}
+	if ran, err := q.RunOnce(ctx); err != nil || ran {
+		t.Fatal(ran, err)
+	}
+
+	// only dead jobs can be retried
+	if err := q.Retry(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if job := server.job(1); job.Status != StatusPending || job.Attempts != 0 || job.LastError != "" {
+		t.Fatal(job)
+	}
+	if err := q.Retry(ctx, 1); err != ErrNoDeadJob {
+		t.Fatal(err)
+	}
+	fail = false
+	if ran, err := q.RunOnce(ctx); err != nil || !ran || server.job(1).Status != StatusDone {
+		t.Fatal(ran, err)
+	}
+}
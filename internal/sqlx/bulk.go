@@ -0,0 +1,180 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"github.com/zzztttkkk/0.0/internal/utils"
+	"reflect"
+	"strings"
+)
+
+// DefaultMaxParams is the max count of params in one statement, for drivers not implementing `MaxParamsDriver`.
+const DefaultMaxParams = 65535
+
+type MaxParamsDriver interface {
+	MaxParams() int
+}
+
+type BulkRows struct {
+	Table   string
+	Columns []string
+	Values  [][]interface{}
+}
+
+// ExtractRows extracts column values from `rows`, a slice of structs or pointers to struct.
+// `tableOrModel` is a table name, a model value, or nil to use the model of `rows`.
+// Columns with `incr` or `default` options are skipped in rows where they are zero, so rows are grouped by
+// the columns written, in order of their first appearance; a zero field takes the default of the column.
+func ExtractRows(tableOrModel any, rows any) ([]*BulkRows, error) {
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("0.0/internal/sqlx: `%T` is not a slice", rows)
+	}
+
+	et := rv.Type().Elem()
+	isPtr := et.Kind() == reflect.Ptr
+	if isPtr {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("0.0/internal/sqlx: `%T` is not a slice of struct", rows)
+	}
+
+	var table string
+	switch tv := tableOrModel.(type) {
+	case string:
+		table = tv
+	case nil:
+		table = tableNameOf(reflect.New(et).Elem())
+	default:
+		mv, err := modelValue(tableOrModel)
+		if err != nil {
+			return nil, err
+		}
+		table = tableNameOf(mv)
+	}
+
+	var groups []*BulkRows
+	var groupIndexes = make(map[string]int)
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		if isPtr {
+			if item.IsNil() {
+				return nil, fmt.Errorf("0.0/internal/sqlx: nil row at %d", i)
+			}
+			item = item.Elem()
+		}
+
+		fields := insertFields(et, []reflect.Value{item})
+		if len(fields) < 1 {
+			return nil, fmt.Errorf("0.0/internal/sqlx: `%s` got empty columns", et)
+		}
+		columns := utils.SliceMap(fields, func(_ int, f *utils.FieldInfo) string { return f.Name })
+		key := strings.Join(columns, ",")
+		idx, ok := groupIndexes[key]
+		if !ok {
+			idx = len(groups)
+			groupIndexes[key] = idx
+			groups = append(groups, &BulkRows{Table: table, Columns: columns})
+		}
+
+		vals := make([]interface{}, 0, len(fields))
+		for _, info := range fields {
+			vals = append(vals, utils.FieldByIndexesReadOnly(item, info.Index).Interface())
+		}
+		groups[idx].Values = append(groups[idx].Values, vals)
+	}
+	return groups, nil
+}
+
+func maxParamsOf(driver Driver) int {
+	if md, ok := driver.(MaxParamsDriver); ok && md.MaxParams() > 0 {
+		return md.MaxParams()
+	}
+	return DefaultMaxParams
+}
+
+// BulkInsert inserts `rows` by multi-row `INSERT` statements, which are split by the max params of the driver.
+// See `ExtractRows` for `tableOrModel` and `rows`.
+func BulkInsert(ctx context.Context, exe Executor, tableOrModel any, rows any) (int64, error) {
+	return bulkInsert(ctx, exe, tableOrModel, rows)
+}
+
+func bulkInsert(ctx context.Context, exe Executor, tableOrModel any, rows any) (int64, error) {
+	if err := InvokeHook(ctx, exe, HookBeforeInsert, rows); err != nil {
+		return 0, err
+	}
+	n, err := doBulkInsert(ctx, exe, tableOrModel, rows)
+	if err != nil {
+		return n, err
+	}
+	return n, InvokeHook(ctx, exe, HookAfterInsert, rows)
+}
+
+func doBulkInsert(ctx context.Context, exe Executor, tableOrModel any, rows any) (int64, error) {
+	groups, err := ExtractRows(tableOrModel, rows)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, br := range groups {
+		n, err := insertRows(ctx, exe, br)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// insertRows inserts `br` by chunks, the params of a chunk are not more than the max params of the driver.
+func insertRows(ctx context.Context, exe Executor, br *BulkRows) (int64, error) {
+	chunkSize := maxParamsOf(exe.Driver()) / len(br.Columns)
+	if chunkSize < 1 {
+		return 0, fmt.Errorf("0.0/internal/sqlx: too many columns, %d", len(br.Columns))
+	}
+
+	dialect := dialectOf(exe)
+	head := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", dialect.Quote(br.Table), quoteJoin(dialect.Quote, br.Columns))
+
+	var total int64
+	var sb strings.Builder
+	for begin := 0; begin < len(br.Values); begin += chunkSize {
+		end := begin + chunkSize
+		if end > len(br.Values) {
+			end = len(br.Values)
+		}
+		chunk := br.Values[begin:end]
+
+		sb.Reset()
+		sb.WriteString(head)
+		args := make([]interface{}, 0, len(chunk)*len(br.Columns))
+		for i, vals := range chunk {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteRune('(')
+			for j := range vals {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				sb.WriteString("${")
+				sb.WriteString(br.Columns[j])
+				sb.WriteRune('}')
+			}
+			sb.WriteRune(')')
+			args = append(args, vals...)
+		}
+
+		result, err := exe.Execute(ctx, sb.String(), args)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,95 @@
+package sqlx
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type _BulkUser struct {
+	Id        int64  `db:"id;incr;primary"`
+	Name      string `db:"name"`
+	CreatedAt int64  `db:"created_at;default=0"`
+}
+
+func TestExtractRows(t *testing.T) {
+	groups, err := ExtractRows(nil, []*_BulkUser{{Name: "a"}, {Name: "b", CreatedAt: 12}, {Name: "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// zero `default` fields are not written as literal zeros
+	if len(groups) != 2 || groups[0].Table != "_bulkuser" || !reflect.DeepEqual(groups[0].Columns, []string{"name"}) ||
+		!reflect.DeepEqual(groups[1].Columns, []string{"name", "created_at"}) {
+		t.Fatalf("%+v", groups)
+	}
+	if !reflect.DeepEqual(groups[0].Values, [][]interface{}{{"a"}, {"c"}}) ||
+		!reflect.DeepEqual(groups[1].Values, [][]interface{}{{"b", int64(12)}}) {
+		t.Fatalf("%+v %+v", groups[0].Values, groups[1].Values)
+	}
+
+	groups, err = ExtractRows("users", []_BulkUser{{Name: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Table != "users" || !reflect.DeepEqual(groups[0].Columns, []string{"name"}) {
+		t.Fatalf("%+v", groups)
+	}
+
+	if groups, err = ExtractRows(nil, []_BulkUser{}); err != nil || len(groups) != 0 {
+		t.Fatal(groups, err)
+	}
+	if _, err = ExtractRows(nil, []int{1}); err == nil {
+		t.Fail()
+	}
+}
+
+type _MaxParamsDriver struct {
+	_TestDriver
+	max int
+}
+
+func (d _MaxParamsDriver) MaxParams() int { return d.max }
+
+func TestBulkInsertChunks(t *testing.T) {
+	ctx := context.Background()
+	rows := make([]_BulkUser, 5)
+	for i := range rows {
+		rows[i] = _BulkUser{Name: "a", CreatedAt: 1}
+	}
+
+	for _, c := range []struct {
+		max    int
+		chunks []int // rows of statements
+	}{
+		{max: 4, chunks: []int{2, 2, 1}}, // exactly two rows
+		{max: 5, chunks: []int{2, 2, 1}}, // one param left
+		{max: 10, chunks: []int{5}},      // exactly all rows
+		{max: 9, chunks: []int{4, 1}},    // one param short
+		{max: 65535, chunks: []int{5}},
+	} {
+		db, conn := newFakeDB()
+		db.driver = _MaxParamsDriver{max: c.max}
+		n, err := db.BulkInsert(ctx, nil, rows)
+		if err != nil || n != int64(len(c.chunks)) {
+			t.Fatal(c.max, n, err)
+		}
+		if len(conn.executed) != len(c.chunks) {
+			t.Fatal(c.max, conn.executed)
+		}
+		for i, q := range conn.executed {
+			if !strings.HasPrefix(q, "INSERT INTO _bulkuser (name, created_at) VALUES (") || strings.Count(q, "(")-1 != c.chunks[i] {
+				t.Fatal(c.max, q)
+			}
+			if strings.Count(q, "$") != c.chunks[i]*2 || strings.Count(q, "$") > c.max {
+				t.Fatal(c.max, q)
+			}
+		}
+	}
+
+	db, _ := newFakeDB()
+	db.driver = _MaxParamsDriver{max: 1}
+	if _, err := db.BulkInsert(ctx, nil, rows); err == nil {
+		t.Fatal("more columns than max params")
+	}
+}
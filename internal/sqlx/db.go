@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"strings"
 )
 
 type DB struct {
@@ -12,10 +11,14 @@ type DB struct {
 	std      *sql.DB
 	logger   Logger
 	driver   Driver
+	hooks    []QueryHook
+	autoLog  bool // hooks[0] is the `LogQueryHook` installed for `logger`
+	stmts    *_StmtCache
 }
 
+// OpenDB opens a db, queries are logged by a `LogQueryHook` if `logger` is not nil.
 func OpenDB(driver Driver, dsn string, readonly bool, logger Logger) (*DB, error) {
-	connector, e := driver.Open(dsn)
+	connector, e := driver.Open(driver.Dialect().NormalizeDSN(dsn))
 	if e != nil {
 		return nil, e
 	}
@@ -26,13 +29,11 @@ func OpenDB(driver Driver, dsn string, readonly bool, logger Logger) (*DB, error
 		logger:   logger,
 		driver:   driver,
 	}
+	db.logQueries()
 	return db, nil
 }
 
 func MustOpenDB(driver Driver, dsn string, readonly bool, logger Logger) *DB {
-	if !strings.HasPrefix(dsn, "postgres://") {
-		dsn = "postgres://" + dsn
-	}
 	db, err := OpenDB(driver, dsn, readonly, logger)
 	if err != nil {
 		panic(err)
@@ -44,6 +45,9 @@ func (db *DB) Driver() Driver {
 	return db.driver
 }
 
+// Logger returns the logger of the db, nil if not set.
+func (db *DB) Logger() Logger { return db.logger }
+
 // DB for interface `Executor`
 func (db *DB) DB() *DB {
 	return db
@@ -52,40 +56,45 @@ func (db *DB) DB() *DB {
 func (db *DB) Raw() *sql.DB { return db.std }
 
 func (db *DB) BindParams(query string, params interface{}) (string, []interface{}, error) {
-	q, keys := ScanParams(query, db.driver)
-	if len(keys) < 1 {
-		return query, nil, nil
-	}
+	q, args, _, err := db.bind(query, params)
+	return q, args, err
+}
 
-	args, err := paramsToArgs(params, keys)
+func (db *DB) bind(query string, params interface{}) (string, []interface{}, []string, error) {
+	q, args, names, err := bindParams(query, db.driver, params)
 	if err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
-
-	if db.logger != nil {
-		db.logger.Printf("%s %v", q, args)
+	if len(args) < 1 {
+		return q, nil, nil, nil
 	}
-	return q, args, nil
+	// args are logged by hooks only, e.g. `LogQueryHook`, which can be wrapped by `RedactArgs`
+	return q, args, names, nil
 }
 
 func (db *DB) Execute(ctx context.Context, query string, params interface{}) (sql.Result, error) {
-	q, a, e := db.BindParams(query, params)
+	q, a, n, e := db.bind(query, params)
 	if e != nil {
 		return nil, e
 	}
-	return db.std.ExecContext(ctx, q, a...)
+	ctx, event := db.beforeQuery(ctx, &QueryEvent{Kind: QueryExec, Query: q, Args: a, Names: n, Stmt: db.usesStmtCache(a)})
+	result, err := db.exec(ctx, nil, q, a)
+	db.afterQuery(ctx, event, result, err)
+	return result, err
 }
 
 func (db *DB) Rows(ctx context.Context, query string, params interface{}) (*Rows, error) {
-	q, a, e := db.BindParams(query, params)
+	q, a, n, e := db.bind(query, params)
 	if e != nil {
 		return nil, e
 	}
-	rows, err := db.std.QueryContext(ctx, q, a...)
+	ctx, event := db.beforeQuery(ctx, &QueryEvent{Kind: QueryRows, Query: q, Args: a, Names: n, Stmt: db.usesStmtCache(a)})
+	rows, err := db.query(ctx, nil, q, a)
+	db.afterQuery(ctx, event, nil, err)
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{Rows: rows}, nil
+	return db.newRows(rows), nil
 }
 
 func (db *DB) FetchOne(ctx context.Context, query string, params interface{}, dist interface{}) error {
@@ -104,9 +113,27 @@ func (db *DB) FetchManyJoined(ctx context.Context, query string, params interfac
 	return selectJoined(ctx, db, query, params, dist, joinedGet)
 }
 
+func (db *DB) BulkInsert(ctx context.Context, tableOrModel any, rows any) (int64, error) {
+	return bulkInsert(ctx, db, tableOrModel, rows)
+}
+
+func (db *DB) Upsert(ctx context.Context, model any, opts *UpsertOptions) (int64, error) {
+	return upsert(ctx, db, model, opts)
+}
+
 var ErrReadonly = errors.New("0.0/internal/sqlx: readonly tx")
 
 func (db *DB) BeginTx(ctx context.Context, opt *sql.TxOptions) (*Tx, error) {
+	return db.begin(ctx, opt, false)
+}
+
+// BeginConnTx likes `BeginTx`, but the transaction is begun on a dedicated connection, which can be accessed
+// by `RawConn`, e.g. for `postgres.CopyFrom`.
+func (db *DB) BeginConnTx(ctx context.Context, opt *sql.TxOptions) (*Tx, error) {
+	return db.begin(ctx, opt, true)
+}
+
+func (db *DB) begin(ctx context.Context, opt *sql.TxOptions, pinConn bool) (*Tx, error) {
 	var readonly = false
 	if opt != nil {
 		readonly = opt.ReadOnly
@@ -115,14 +142,34 @@ func (db *DB) BeginTx(ctx context.Context, opt *sql.TxOptions) (*Tx, error) {
 		return nil, ErrReadonly
 	}
 
-	tx, err := db.std.BeginTx(ctx, opt)
-	if err != nil {
+	hctx, event := db.beforeQuery(ctx, &QueryEvent{Kind: QueryTxBegin, Query: "BEGIN"})
+	tx, err := db.beginTx(ctx, opt, readonly, pinConn)
+	if event != nil {
+		event.Tx = tx
+	}
+	db.afterQuery(hctx, event, nil, err)
+	return tx, err
+}
+
+func (db *DB) beginTx(ctx context.Context, opt *sql.TxOptions, readonly, pinConn bool) (*Tx, error) {
+	var conn *sql.Conn
+	var tx *sql.Tx
+	var err error
+	if pinConn {
+		if conn, err = db.std.Conn(ctx); err != nil {
+			return nil, err
+		}
+		if tx, err = conn.BeginTx(ctx, opt); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	} else if tx, err = db.std.BeginTx(ctx, opt); err != nil {
 		return nil, err
 	}
 	if db.logger != nil {
 		db.logger.Printf("0.0/internal/sqlx: tx begin, Tx(%p);", tx)
 	}
-	return &Tx{std: tx, db: db, ctx: ctx, readonly: readonly}, nil
+	return &Tx{std: tx, conn: conn, db: db, ctx: ctx, readonly: readonly}, nil
 }
 
 func (db *DB) MustBeginTx(ctx context.Context, opt *sql.TxOptions) *Tx {
@@ -134,8 +181,13 @@ func (db *DB) MustBeginTx(ctx context.Context, opt *sql.TxOptions) *Tx {
 }
 
 func (db *DB) Prepare(ctx context.Context, query string) (*Stmt, error) {
+	if hasExpandParams(query) {
+		return nil, ErrExpandInStmt
+	}
 	query, keys := ScanParams(query, db.driver)
-	stmt, err := db.std.PrepareContext(ctx, query)
+	hctx, event := db.beforeQuery(ctx, &QueryEvent{Kind: QueryPrepare, Query: query, Stmt: true})
+	stmt, err := db.std.PrepareContext(hctx, query)
+	db.afterQuery(hctx, event, nil, err)
 	if err != nil {
 		return nil, err
 	}
@@ -145,8 +197,10 @@ func (db *DB) Prepare(ctx context.Context, query string) (*Stmt, error) {
 	return &Stmt{
 		std:    stmt,
 		keys:   keys,
+		query:  query,
+		db:     db,
 		logger: db.logger,
 	}, nil
 }
 
-var _ Executor = (*DB)(nil)
+var _ BulkExecutor = (*DB)(nil)
@@ -34,6 +34,14 @@ type IndexField struct {
 
 type IndexInfo struct {
 	Fields []*IndexField
+	IndexOptions
+}
+
+// IndexOptions can be returned by the model method `IndexOptions() map[string]sqlx.IndexOptions`, keyed by index name.
+type IndexOptions struct {
+	Using   string   // index method, `gin`, `gist`, `brin`, etc.
+	Where   string   // makes a partial index
+	Include []string // non-key columns
 }
 
 type FieldDefinition struct {
@@ -45,6 +53,13 @@ type FieldDefinition struct {
 	Nullable   bool
 	Unique     bool
 	Indexes    []IndexField
+	References string // `table(column)`
+	OnDelete   string
+	OnUpdate   string
+	Generated  string // the expression of a stored generated column
+	// Before are the statements the column depends on, e.g. creating its enum type,
+	// they are executed before the table or the column is created.
+	Before []string
 }
 
 func (fd *FieldDefinition) AppendIndex(field IndexField) {
@@ -65,7 +80,7 @@ func (fd *FieldDefinition) CheckAnd(v string, args ...any) {
 }
 
 func (fd *FieldDefinition) CheckOr(v string, args ...any) {
-	v = fmt.Sprintf(v, args)
+	v = fmt.Sprintf(v, args...)
 
 	if len(fd.Check) < 1 {
 		fd.Check = v
@@ -75,20 +90,28 @@ func (fd *FieldDefinition) CheckOr(v string, args ...any) {
 }
 
 func (db *DB) TableName(val reflect.Value) string {
-	var tablename string
-	if tableNameFn := val.MethodByName("TableName"); tableNameFn.IsValid() {
-		if fn, _ := tableNameFn.Interface().(func() string); fn != nil {
-			tablename = fn()
-		}
-	}
-	if len(tablename) < 1 {
-		tablename = strings.ToLower(val.Type().Name())
-	}
-	return tablename
+	return tableNameOf(val)
 }
 
 func (db *DB) DropTable(ctx context.Context, name string) error {
-	_, err := db.Execute(ctx, fmt.Sprintf("%s TABLE %s", "DROP", name), nil)
+	_, err := db.Execute(ctx, db.driver.Dialect().DropTable(name), nil)
+	return err
+}
+
+// AddColumn adds the column of model `v` named `column` to its table.
+func (db *DB) AddColumn(ctx context.Context, v any, column string) error {
+	tablename, _, _, columns := tableDDL(db.driver, v)
+	fd, ok := columns[column]
+	if !ok {
+		return fmt.Errorf("0.0/internal/sqlx: `%s` is not a column of `%s`", column, tablename)
+	}
+	for _, stmt := range fd.Before {
+		if _, err := db.Execute(ctx, stmt, nil); err != nil {
+			return err
+		}
+	}
+	dialect := db.driver.Dialect()
+	_, err := db.Execute(ctx, dialect.AddColumn(tablename, columnDDL(dialect.Quote, fd)), nil)
 	return err
 }
 
@@ -164,46 +187,113 @@ func appendIndex(m map[string]*IndexInfo, f *IndexField) {
 	ii.Fields = append(ii.Fields, f)
 }
 
-func (db *DB) ensureIndexes(ctx context.Context, tablename string, m map[string]*IndexInfo) error {
+func indexDDL(quote func(string) string, tablename, name string, info *IndexInfo) string {
 	var sb strings.Builder
-	for name, info := range m {
-		sb.Reset()
+	sort.Slice(info.Fields, func(i, j int) bool { return info.Fields[i].SortInIndex < info.Fields[j].SortInIndex })
 
-		sort.Slice(info.Fields, func(i, j int) bool { return info.Fields[i].SortInIndex < info.Fields[j].SortInIndex })
+	sb.WriteString("CREATE ")
+	if strings.HasSuffix(name, "unique") {
+		sb.WriteString("UNIQUE ")
+	}
+	sb.WriteString("INDEX IF NOT EXISTS ")
+	sb.WriteString(quote(name))
+	sb.WriteString(" ON ")
+	sb.WriteString(quote(tablename))
+	if len(info.Using) > 0 {
+		sb.WriteString(" USING ")
+		sb.WriteString(info.Using)
+	}
+	sb.WriteString("(\r\n")
 
-		sb.WriteString("CREATE ")
-		if strings.HasSuffix(name, "unique") {
-			sb.WriteString("UNIQUE ")
-		}
-		sb.WriteString("INDEX IF NOT EXISTS ")
-		sb.WriteString(name)
-		sb.WriteString(" ON ")
-		sb.WriteString(tablename)
-		sb.WriteString("(\r\n")
-
-		for i, f := range info.Fields {
-			sb.WriteRune('\t')
-			sb.WriteString(f.FieldName)
+	// only btree indexes support ordering
+	ordered := len(info.Using) < 1 || strings.EqualFold(info.Using, "btree")
+	for i, f := range info.Fields {
+		sb.WriteRune('\t')
+		sb.WriteString(quote(f.FieldName))
+		if ordered {
 			switch f.OrderType {
 			case IndexFieldOrderAsc:
 				sb.WriteString(" ASC")
 			default:
 				sb.WriteString(" DESC")
 			}
-			if i < len(info.Fields)-1 {
-				sb.WriteString(",\r\n")
-			}
 		}
-		sb.WriteString("\r\n);")
-
-		if _, err := db.Execute(ctx, sb.String(), nil); err != nil {
-			return err
+		if i < len(info.Fields)-1 {
+			sb.WriteString(",\r\n")
 		}
 	}
-	return nil
+	sb.WriteString("\r\n)")
+	if len(info.Include) > 0 {
+		sb.WriteString(" INCLUDE (")
+		sb.WriteString(strings.Join(utils.SliceMap(info.Include, func(_ int, c string) string { return quote(c) }), ", "))
+		sb.WriteRune(')')
+	}
+	if len(info.Where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(info.Where)
+	}
+	sb.WriteRune(';')
+	return sb.String()
 }
 
-func (db *DB) CreateTable(ctx context.Context, v any) error {
+var fkActions = map[string]bool{
+	"CASCADE":     true,
+	"RESTRICT":    true,
+	"SET NULL":    true,
+	"SET DEFAULT": true,
+	"NO ACTION":   true,
+}
+
+func fkAction(v string) string {
+	v = strings.ToUpper(strings.Join(strings.Fields(v), " "))
+	if !fkActions[v] {
+		panic(fmt.Errorf("0.0/internal/sqlx: bad foreign key action, `%s`", v))
+	}
+	return v
+}
+
+// uniqueIndexName returns the name of the partial unique index replacing an unique constraint.
+func uniqueIndexName(tablename, name string) string {
+	name = fmt.Sprintf("%s_%s", tablename, name)
+	if !strings.HasSuffix(name, "unique") {
+		name += "_unique"
+	}
+	return name
+}
+
+func callModelMethod[T any](val reflect.Value, name string) (T, bool) {
+	var zero T
+	mv := val.MethodByName(name)
+	if !mv.IsValid() {
+		return zero, false
+	}
+	fn, ok := mv.Interface().(func() T)
+	if !ok {
+		return zero, false
+	}
+	return fn(), true
+}
+
+// TableDDL returns the `CREATE TABLE` statement and the `CREATE INDEX` statements of model `v`.
+//
+// Besides field tags, a model can declare table-level constraints by the method `TableConstraints() []string`,
+// and index options by the method `IndexOptions() map[string]sqlx.IndexOptions`.
+func (db *DB) TableDDL(v any) (string, []string) { return TableDDL(db.driver, v) }
+
+// TableDDL likes `DB.TableDDL`, but it does not need an opened database.
+func TableDDL(driver Driver, v any) (string, []string) {
+	tablename, ddl, indexes, _ := tableDDL(driver, v)
+	names := utils.MapKeys(indexes)
+	sort.Strings(names)
+	stmts := utils.SliceMap(names, func(_ int, name string) string {
+		return driver.Dialect().CreateIndex(tablename, name, indexes[name])
+	})
+	return ddl, utils.SliceFilter(stmts, func(v string) bool { return len(v) > 0 })
+}
+
+// tableDDL returns the table name, the `CREATE TABLE` statement(following the `Before` statements of columns),
+// the indexes and the definitions of columns.
+func tableDDL(driver Driver, v any) (string, string, map[string]*IndexInfo, map[string]*FieldDefinition) {
 	val := reflect.ValueOf(v)
 	if val.Kind() == reflect.Ptr {
 		val = val.Elem()
@@ -212,13 +302,15 @@ func (db *DB) CreateTable(ctx context.Context, v any) error {
 		panic(fmt.Errorf("0.0/internal/sqlx: `%+v` is not a struct", v))
 	}
 
-	smap := DBReflectMapper.TypeMap(val.Type())
+	tablename := tableNameOf(val)
+	softDelete := softDeleteField(val.Type())
+	quote := driver.Dialect().Quote
+
 	var fields []*FieldDefinition
 	var indexes = make(map[string]*IndexInfo)
-	for _, info := range smap.Index {
-		if info.Path != info.Name || info.Embedded {
-			continue
-		}
+	var uniqueGroups = make(map[string][]string)
+	var uniqueGroupNames []string
+	for _, info := range modelFields(val.Type()) {
 		var fd *FieldDefinition
 		fv := val.MethodByName(fmt.Sprintf("DDL%s", info.Field.Name))
 		if fv.IsValid() && fv.Type().Out(0) == reflect.TypeOf(fd) {
@@ -228,29 +320,71 @@ func (db *DB) CreateTable(ctx context.Context, v any) error {
 			}
 		}
 		if fd == nil {
-			fd = db.driver.DDL(info)
+			fd = driver.DDL(info)
 		}
 
 		if _, ok := info.Options["nullable"]; ok {
 			fd.Nullable = true
 		}
 
-		if _, ok := info.Options["unique"]; ok {
-			fd.Unique = true
+		if group, ok := info.Options["unique"]; ok {
+			_, primary := info.Options["primary"]
+			switch {
+			case len(group) > 0:
+				if _, exists := uniqueGroups[group]; !exists {
+					uniqueGroupNames = append(uniqueGroupNames, group)
+				}
+				uniqueGroups[group] = append(uniqueGroups[group], info.Name)
+			case softDelete != nil && !primary:
+				// deleted rows should not block the value from being reused
+				appendIndex(indexes, &IndexField{
+					IndexName: uniqueIndexName(tablename, info.Name),
+					FieldName: info.Name,
+					OrderType: IndexFieldOrderAsc,
+				})
+			default:
+				fd.Unique = true
+			}
 		}
 
 		if _, ok := info.Options["primary"]; ok {
 			fd.PrimaryKey = true
 		}
 
-		if dv, ok := info.Options["default"]; ok {
+		// drivers may set a translated default, e.g. of a postgres function
+		if dv, ok := info.Options["default"]; ok && len(fd.Default) < 1 {
 			fd.Default = dv
 		}
 
+		if cv, ok := info.Options["check"]; ok && len(cv) > 0 {
+			fd.CheckAnd("%s", cv)
+		}
+
+		if rv, ok := info.Options["references"]; ok && len(rv) > 0 {
+			fd.References = rv
+			if av := info.Options["ondelete"]; len(av) > 0 {
+				fd.OnDelete = fkAction(av)
+			}
+			if av := info.Options["onupdate"]; len(av) > 0 {
+				fd.OnUpdate = fkAction(av)
+			}
+		}
+
 		for _, ief := range parseIndex(info.Name, info.Options["index"]) {
 			appendIndex(indexes, ief)
 		}
 
+		// `using=gin` makes a single-column index by the method, `fts` columns are indexed by gin by default
+		using := info.Options["using"]
+		if _, ok := info.Options["fts"]; ok && len(using) < 1 {
+			using = "gin"
+		}
+		if len(using) > 0 {
+			name := fmt.Sprintf("%s_%s_%s", tablename, info.Name, using)
+			appendIndex(indexes, &IndexField{IndexName: name, FieldName: info.Name, OrderType: IndexFieldOrderAsc})
+			indexes[name].Using = using
+		}
+
 		fd.Name = info.Name
 		fields = append(fields, fd)
 	}
@@ -276,51 +410,197 @@ func (db *DB) CreateTable(ctx context.Context, v any) error {
 		panic(fmt.Errorf("0.0/internal/sqlx: `%+v` got empty primary keys", v))
 	}
 
-	tablename := db.TableName(val)
+	var constraints []string
+	for _, group := range uniqueGroupNames {
+		if softDelete != nil {
+			name := uniqueIndexName(tablename, group)
+			for _, c := range uniqueGroups[group] {
+				appendIndex(indexes, &IndexField{IndexName: name, FieldName: c, OrderType: IndexFieldOrderAsc})
+			}
+			continue
+		}
+		constraints = append(constraints, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", quote(group), quoteJoin(quote, uniqueGroups[group])))
+	}
+	if tcs, ok := callModelMethod[[]string](val, "TableConstraints"); ok {
+		constraints = append(constraints, tcs...)
+	}
 
+	if ios, ok := callModelMethod[map[string]IndexOptions](val, "IndexOptions"); ok {
+		for name, opts := range ios {
+			info := indexes[name]
+			if info == nil {
+				panic(fmt.Errorf("0.0/internal/sqlx: unknown index `%s` in IndexOptions", name))
+			}
+			if len(opts.Using) < 1 {
+				opts.Using = info.Using
+			}
+			info.IndexOptions = opts
+		}
+	}
+
+	if softDelete != nil {
+		for name, info := range indexes {
+			if !strings.HasSuffix(name, "unique") {
+				continue
+			}
+			if len(info.Where) > 0 {
+				info.Where = and(info.Where, aliveCondition(quote, softDelete))
+			} else {
+				info.Where = aliveCondition(quote, softDelete)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	var columns = make(map[string]*FieldDefinition, len(fields))
+	var befores = make(map[string]bool)
+	for _, field := range fields {
+		columns[field.Name] = field
+		for _, stmt := range field.Before {
+			if befores[stmt] {
+				continue
+			}
+			befores[stmt] = true
+			sb.WriteString(stmt)
+			sb.WriteString(";\r\n")
+		}
+	}
+	sb.WriteString(driver.Dialect().CreateTable(&TableDefinition{
+		Name:        tablename,
+		Columns:     fields,
+		PrimaryKeys: utils.SliceMap(primaryKeys, func(_ int, fd *FieldDefinition) string { return fd.Name }),
+		Constraints: constraints,
+	}))
+	return tablename, sb.String(), indexes, columns
+}
+
+// TableDefinition is the table rendered by `Dialect.CreateTable`.
+type TableDefinition struct {
+	Name        string
+	Columns     []*FieldDefinition
+	PrimaryKeys []string
+	Constraints []string // table constraints, foreign keys of columns are rendered before them
+}
+
+// CreateTableStmt renders the `CREATE TABLE IF NOT EXISTS` statement of `table`, identifiers are quoted by `quote`.
+func CreateTableStmt(quote func(string) string, table *TableDefinition) string {
 	var sb strings.Builder
 	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
-	sb.WriteString(tablename)
+	sb.WriteString(quote(table.Name))
 	sb.WriteString(" (\r\n")
-
-	for _, field := range fields {
+	for _, field := range table.Columns {
 		sb.WriteRune('\t')
-		sb.WriteString(field.Name)
-		sb.WriteRune(' ')
-		sb.WriteString(field.SqlType)
+		sb.WriteString(columnDDL(quote, field))
+		sb.WriteString(",\r\n")
+	}
 
-		if field.Unique {
-			sb.WriteString(" UNIQUE")
+	// foreign keys are table constraints, mysql ignores the column-level `REFERENCES`
+	var fks []string
+	for _, field := range table.Columns {
+		if len(field.References) < 1 {
+			continue
 		}
-
-		if !field.Nullable {
-			sb.WriteString(" NOT NULL")
+		fk := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", quote(field.Name), field.References)
+		if len(field.OnDelete) > 0 {
+			fk += " ON DELETE " + field.OnDelete
 		}
-
-		if len(field.Check) > 0 {
-			sb.WriteString(" CHECK (")
-			sb.WriteString(field.Check)
-			sb.WriteRune(')')
+		if len(field.OnUpdate) > 0 {
+			fk += " ON UPDATE " + field.OnUpdate
 		}
+		fks = append(fks, fk)
+	}
 
-		if len(field.Default) > 0 {
-			sb.WriteString(" DEFAULT ")
-			sb.WriteString(field.Default)
-		}
+	sb.WriteString("\tprimary key (")
+	sb.WriteString(strings.Join(utils.SliceMap(table.PrimaryKeys, func(_ int, name string) string { return quote(name) }), ","))
+	sb.WriteRune(')')
+	for _, c := range append(fks, table.Constraints...) {
+		sb.WriteString(",\r\n\t")
+		sb.WriteString(c)
+	}
+	sb.WriteString("\r\n);\r\n")
+	return sb.String()
+}
 
-		sb.WriteString(",\r\n")
+func columnDDL(quote func(string) string, field *FieldDefinition) string {
+	var sb strings.Builder
+	sb.WriteString(quote(field.Name))
+	sb.WriteRune(' ')
+	sb.WriteString(field.SqlType)
+
+	if len(field.Generated) > 0 {
+		sb.WriteString(" GENERATED ALWAYS AS (")
+		sb.WriteString(field.Generated)
+		sb.WriteString(") STORED")
 	}
 
-	sb.WriteString("\tprimary key (")
-	sb.WriteString(strings.Join(utils.SliceMap(primaryKeys, func(_ int, fd *FieldDefinition) string { return fd.Name }), ","))
-	sb.WriteString(")\r\n);\r\n")
+	if field.Unique {
+		sb.WriteString(" UNIQUE")
+	}
+
+	if !field.Nullable {
+		sb.WriteString(" NOT NULL")
+	}
 
-	ddl := sb.String()
+	if len(field.Check) > 0 {
+		sb.WriteString(" CHECK (")
+		sb.WriteString(field.Check)
+		sb.WriteRune(')')
+	}
+
+	if len(field.Default) > 0 {
+		sb.WriteString(" DEFAULT ")
+		sb.WriteString(field.Default)
+	}
+	return sb.String()
+}
+
+// EnsureExtensions creates extensions `exts` if not exist, the dialect should implement `ExtensionCreator`.
+func (db *DB) EnsureExtensions(ctx context.Context, exts ...string) error {
+	if len(exts) < 1 {
+		return nil
+	}
+	creator, ok := db.driver.Dialect().(ExtensionCreator)
+	if !ok {
+		return ErrExtensionUnsupported
+	}
+	for _, name := range exts {
+		if _, err := db.Execute(ctx, creator.CreateExtension(name), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) CreateTable(ctx context.Context, v any) error {
+	tablename, ddl, indexes, _ := tableDDL(db.driver, v)
 	if db.logger != nil {
 		db.logger.Printf(ddl)
 	}
 	if _, err := db.Execute(ctx, ddl, nil); err != nil {
 		return err
 	}
-	return db.ensureIndexes(ctx, tablename, indexes)
+
+	dialect := db.driver.Dialect()
+	checker, _ := dialect.(IndexChecker)
+	names := utils.MapKeys(indexes)
+	sort.Strings(names)
+	for _, name := range names {
+		if checker != nil {
+			exists, err := checker.IndexExists(ctx, db, tablename, name)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+		}
+		stmt := dialect.CreateIndex(tablename, name, indexes[name])
+		if len(stmt) < 1 {
+			continue
+		}
+		if _, err := db.Execute(ctx, stmt, nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,129 @@
+package sqlx
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zzztttkkk/0.0/internal/utils"
+)
+
+type _DDLDriver struct {
+	_TestDriver
+}
+
+func (_ _DDLDriver) DDL(info *utils.FieldInfo) *FieldDefinition {
+	return &FieldDefinition{SqlType: strings.ToUpper(info.Field.Type.Kind().String())}
+}
+
+type _DDLMember struct {
+	Id        int64  `db:"id;primary"`
+	OrgId     int64  `db:"org_id;unique=member_org_user;references=orgs(id);ondelete=cascade"`
+	UserId    int64  `db:"user_id;unique=member_org_user;references=users(id);ondelete=set null"`
+	Role      string `db:"role;check=role <> '';index=member_role,asc"`
+	Tags      string `db:"tags;index=member_tags"`
+	DeletedAt int64  `db:"deleted_at;softdelete"`
+}
+
+func (_ _DDLMember) TableName() string { return "members" }
+
+func (_ _DDLMember) TableConstraints() []string {
+	return []string{"CHECK (org_id <> user_id)"}
+}
+
+func (_ _DDLMember) IndexOptions() map[string]IndexOptions {
+	return map[string]IndexOptions{"member_tags": {Using: "gin"}, "member_role": {Include: []string{"user_id"}}}
+}
+
+func TestTableDDL(t *testing.T) {
+	db := &DB{driver: _DDLDriver{}}
+	table, indexes := db.TableDDL(_DDLMember{})
+	for _, v := range []string{
+		"role STRING NOT NULL CHECK (role <> '')",
+		"\tFOREIGN KEY (org_id) REFERENCES orgs(id) ON DELETE CASCADE,\r\n",
+		"\tFOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,\r\n",
+		"\tCHECK (org_id <> user_id)\r\n);",
+	} {
+		if !strings.Contains(table, v) {
+			t.Fatalf("%q not in %s", v, table)
+		}
+	}
+	if strings.Contains(table, "UNIQUE") {
+		t.Fatal(table)
+	}
+
+	all := strings.Join(indexes, "\n")
+	for _, v := range []string{
+		"CREATE UNIQUE INDEX IF NOT EXISTS members_member_org_user_unique ON members(\r\n\torg_id ASC,\r\n\tuser_id ASC\r\n) WHERE deleted_at = 0;",
+		"CREATE INDEX IF NOT EXISTS member_tags ON members USING gin(\r\n\ttags\r\n);",
+		"ON members(\r\n\trole ASC\r\n) INCLUDE (user_id);",
+	} {
+		if !strings.Contains(all, v) {
+			t.Fatalf("%q not in %s", v, all)
+		}
+	}
+}
+
+func TestGroupConflictTarget(t *testing.T) {
+	target := conflictTarget(reflect.TypeOf(_DDLMember{}), []string{"org_id", "user_id", "role"})
+	if !reflect.DeepEqual(target, []string{"org_id", "user_id"}) {
+		t.Fatal(target)
+	}
+}
+
+func TestFkAction(t *testing.T) {
+	if fkAction("set  null") != "SET NULL" {
+		t.Fail()
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fail()
+		}
+	}()
+	fkAction("drop")
+}
+
+type _DDLOrder struct {
+	Id    int64 `db:"id;primary"`
+	Group int64 `db:"group;index=order_group"`
+}
+
+func (_ _DDLOrder) TableName() string { return "order" }
+
+func TestQuoteIdent(t *testing.T) {
+	cases := map[string]string{
+		"account":        "account",
+		"Account":        "Account",
+		"public.account": "public.account",
+		"user":           `"user"`,
+		"public.user":    `public."user"`,
+		`"user"`:         `"user"`,
+		"my-table":       `"my-table"`,
+		`a"b`:            `"a""b"`,
+		"1st":            `"1st"`,
+	}
+	for ident, expected := range cases {
+		if v := QuoteIdent(ident, '"'); v != expected {
+			t.Fatalf("%s => %s", ident, v)
+		}
+	}
+
+	table, indexes := TableDDL(_DDLDriver{}, _DDLOrder{})
+	if !strings.Contains(table, `CREATE TABLE IF NOT EXISTS "order" (`) || !strings.Contains(table, `"group" INT64 NOT NULL`) {
+		t.Fatal(table)
+	}
+	if len(indexes) != 1 || !strings.Contains(indexes[0], `ON "order"(`+"\r\n\t"+`"group" DESC`) {
+		t.Fatal(indexes)
+	}
+}
+
+func TestEnsureExtensions(t *testing.T) {
+	db, _ := newFakeDB()
+	if err := db.EnsureExtensions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.EnsureExtensions(context.Background(), "hstore"); err != ErrExtensionUnsupported {
+		t.Fatal(err)
+	}
+}
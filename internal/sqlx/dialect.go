@@ -0,0 +1,153 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zzztttkkk/0.0/internal/utils"
+)
+
+// Dialect owns the sql syntax which differs between databases, the generic layer generates sql by it.
+type Dialect interface {
+	// Quote quotes `ident` if it is a reserved word or not a plain identifier, `schema.table` is quoted part by part.
+	// Plain identifiers are kept unquoted, so that the case folding of the database is not changed.
+	Quote(ident string) string
+	// NormalizeDSN is called before the dsn is passed to `Driver.Open`.
+	NormalizeDSN(dsn string) string
+	// Returning reports whether `INSERT ... RETURNING` is supported.
+	Returning() bool
+	// CreateIndex returns an empty statement if the index is skipped by the database.
+	CreateIndex(tablename, name string, info *IndexInfo) string
+	DropTable(tablename string) string
+	// AddColumn returns the statement adding the column, `column` is the rendered column definition.
+	AddColumn(tablename, column string) string
+	// CreateTable returns the `CREATE TABLE IF NOT EXISTS` statement of `table`.
+	CreateTable(table *TableDefinition) string
+	// Upsert returns the clause following `INSERT INTO tablename (...) VALUES (...)`, which handles the conflict.
+	Upsert(tablename string, clause *UpsertClause) string
+}
+
+// UpsertClause is the conflict handling rendered by `Dialect.Upsert`, names are not quoted.
+type UpsertClause struct {
+	Target  []string // the conflict target
+	Where   string   // infers partial unique indexes, quoted already
+	Updates []string // columns set to the inserted values, the conflicting row is unchanged if empty
+	Version string   // the version column increased on update, optional
+}
+
+// ExtensionCreator can be implemented by dialects which support extensions, see `DB.EnsureExtensions`.
+type ExtensionCreator interface {
+	CreateExtension(name string) string
+}
+
+// IndexChecker can be implemented by dialects whose `CreateIndex` statement is not idempotent,
+// it is called before creating the index.
+type IndexChecker interface {
+	IndexExists(ctx context.Context, exe Executor, tablename, name string) (bool, error)
+}
+
+var ErrReturningUnsupported = errors.New("0.0/internal/sqlx: `RETURNING` is not supported by the dialect")
+var ErrExtensionUnsupported = errors.New("0.0/internal/sqlx: extensions are not supported by the dialect")
+
+// reservedWords are keywords reserved by at least one of postgres, mysql and sqlite, which are likely to be
+// used as table or column names.
+var reservedWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		all analyse analyze and any array as asc asymmetric between both by case cast check collate column constraint
+		create cross current_date current_time current_timestamp current_user default delete desc distinct do drop
+		else end except exists false fetch for foreign from full grant group having in index inner insert intersect
+		interval into is join key keys lateral leading left like limit localtime localtimestamp match natural not
+		null offset on only or order outer placing primary range references returning right row rows select
+		session_user set some symmetric table then to trailing true union unique update user using values variadic
+		when where window with
+	`) {
+		reservedWords[w] = true
+	}
+}
+
+func isPlainIdent(ident string) bool {
+	if len(ident) < 1 || (ident[0] >= '0' && ident[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(ident); i++ {
+		if !isIdentByte(ident[i]) {
+			return false
+		}
+	}
+	return !reservedWords[strings.ToLower(ident)]
+}
+
+// QuoteIdent quotes `ident` by `quote` like `Dialect.Quote`, the quote char in names is doubled.
+func QuoteIdent(ident string, quote byte) string {
+	parts := strings.Split(ident, ".")
+	for i, part := range parts {
+		if isPlainIdent(part) || (len(part) > 1 && part[0] == quote && part[len(part)-1] == quote) {
+			continue
+		}
+		q := string(quote)
+		parts[i] = q + strings.ReplaceAll(part, q, q+q) + q
+	}
+	return strings.Join(parts, ".")
+}
+
+// DefaultDialect is the standard dialect postgres follows, drivers can embed it and override what differs.
+type DefaultDialect struct{}
+
+func (DefaultDialect) Quote(ident string) string { return QuoteIdent(ident, '"') }
+
+func (DefaultDialect) NormalizeDSN(dsn string) string { return dsn }
+
+func (DefaultDialect) Returning() bool { return true }
+
+func (d DefaultDialect) CreateIndex(tablename, name string, info *IndexInfo) string {
+	return indexDDL(d.Quote, tablename, name, info)
+}
+
+func (d DefaultDialect) DropTable(tablename string) string {
+	return fmt.Sprintf("DROP TABLE %s", d.Quote(tablename))
+}
+
+func (d DefaultDialect) AddColumn(tablename, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.Quote(tablename), column)
+}
+
+func (d DefaultDialect) CreateTable(table *TableDefinition) string {
+	return CreateTableStmt(d.Quote, table)
+}
+
+// Upsert renders `ON CONFLICT (target) [WHERE ...] DO UPDATE SET c = EXCLUDED.c` or `DO NOTHING`.
+func (d DefaultDialect) Upsert(tablename string, clause *UpsertClause) string {
+	var sb strings.Builder
+	sb.WriteString("ON CONFLICT (")
+	sb.WriteString(quoteJoin(d.Quote, clause.Target))
+	sb.WriteRune(')')
+	if len(clause.Where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(clause.Where)
+	}
+	if len(clause.Updates) < 1 {
+		sb.WriteString(" DO NOTHING")
+		return sb.String()
+	}
+	sb.WriteString(" DO UPDATE SET ")
+	sb.WriteString(strings.Join(utils.SliceMap(clause.Updates, func(_ int, c string) string {
+		return fmt.Sprintf("%s = EXCLUDED.%s", d.Quote(c), d.Quote(c))
+	}), ", "))
+	if len(clause.Version) > 0 {
+		sb.WriteString(fmt.Sprintf(", %s = %s.%s + 1", d.Quote(clause.Version), d.Quote(tablename), d.Quote(clause.Version)))
+	}
+	return sb.String()
+}
+
+var _ Dialect = DefaultDialect{}
+
+func dialectOf(exe interface{}) Dialect {
+	if v, ok := exe.(interface{ Driver() Driver }); ok && v.Driver() != nil {
+		return v.Driver().Dialect()
+	}
+	return DefaultDialect{}
+}
@@ -9,4 +9,41 @@ type Driver interface {
 	Open(dsn string) (driver.Connector, error)
 	Placeholder(idx int, name string) string
 	DDL(info *utils.FieldInfo) *FieldDefinition
+	Dialect() Dialect
+}
+
+// ScanWrapper can be implemented by drivers whose values can not be converted to some field types by database/sql,
+// e.g. postgres arrays. `WrapScan` is called with the pointer of every scanned field, and returns the scan
+// destination of it, usually a `sql.Scanner` or the pointer itself.
+type ScanWrapper interface {
+	WrapScan(ptr any) any
+}
+
+// ArgConverter can be implemented by drivers to convert args which are not supported by the database/sql driver,
+// e.g. binding `map[string]string` as postgres hstore. It returns false if `arg` is kept as is.
+type ArgConverter interface {
+	ConvertArg(arg any) (any, bool)
+}
+
+// convertArgs returns the args converted by `driver`, `args` is not modified.
+func convertArgs(driver Driver, args []interface{}) []interface{} {
+	c, ok := driver.(ArgConverter)
+	if !ok {
+		return args
+	}
+	var converted []interface{}
+	for i, arg := range args {
+		v, ok := c.ConvertArg(arg)
+		if !ok {
+			continue
+		}
+		if converted == nil {
+			converted = append([]interface{}(nil), args...)
+		}
+		converted[i] = v
+	}
+	if converted == nil {
+		return args
+	}
+	return converted
 }
@@ -3,6 +3,7 @@ package sqlx
 import (
 	"context"
 	"database/sql"
+	"errors"
 )
 
 type BasicExecutor interface {
@@ -22,6 +23,14 @@ type Executor interface {
 	Driver() Driver
 }
 
+// BulkExecutor is implemented by `DB`, `Tx` and `Group`. It is not a part of `Executor`, so that other
+// implementations of `Executor` keep working, the functions `BulkInsert` and `Upsert` accept any `Executor`.
+type BulkExecutor interface {
+	Executor
+	BulkInsert(ctx context.Context, tableOrModel any, rows any) (int64, error)
+	Upsert(ctx context.Context, model any, opts *UpsertOptions) (int64, error)
+}
+
 func fetchOne(ctx context.Context, be BasicExecutor, query string, params interface{}, dist interface{}) error {
 	rows, err := be.Rows(ctx, query, params)
 	if err != nil {
@@ -57,3 +66,22 @@ func selectJoined(ctx context.Context, be BasicExecutor, query string, params in
 	defer rows.Close()
 	return rows.selectJoined(slicePtr, joinedGet)
 }
+
+var ErrNoRawConn = errors.New("0.0/internal/sqlx: the connection of the tx is not accessible, begin it by `BeginConnTx`")
+
+// RawConn calls `fn` with the driver connection used by `exe`, the connection of the transaction if `exe` is a `Tx`,
+// which should be begun by `DB.BeginConnTx`.
+func RawConn(ctx context.Context, exe Executor, fn func(driverConn any) error) error {
+	if tx, ok := exe.(*Tx); ok {
+		if tx.conn == nil {
+			return ErrNoRawConn
+		}
+		return tx.conn.Raw(fn)
+	}
+	conn, err := exe.DB().std.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(fn)
+}
@@ -0,0 +1,81 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+// newDist returns a new `T` and the scan destination of it, a pointer to struct `T` is allocated.
+func newDist[T any]() (*T, interface{}) {
+	var v T
+	if vt := reflect.TypeOf(v); vt != nil && vt.Kind() == reflect.Ptr && vt.Elem().Kind() == reflect.Struct {
+		ptrV := reflect.New(vt.Elem())
+		reflect.ValueOf(&v).Elem().Set(ptrV)
+		return &v, ptrV.Interface()
+	}
+	return &v, &v
+}
+
+// One returns the first result row as `T`, or `sql.ErrNoRows`.
+func One[T any](ctx context.Context, exe BasicExecutor, query string, params interface{}) (T, error) {
+	v, dist := newDist[T]()
+	rows, err := exe.Rows(ctx, query, params)
+	if err != nil {
+		return *v, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		return *v, err
+	}
+	err = rows.doScan(dist, nil, nil)
+	return *v, err
+}
+
+// Many returns all result rows as `[]T`.
+func Many[T any](ctx context.Context, exe BasicExecutor, query string, params interface{}) ([]T, error) {
+	rows, err := exe.Rows(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var temp = make([]interface{}, 0, len(columns))
+
+	var lst []T
+	for rows.Next() {
+		v, dist := newDist[T]()
+		if err = rows.doScan(dist, columns, &temp); err != nil {
+			return nil, err
+		}
+		lst = append(lst, *v)
+	}
+	return lst, rows.Err()
+}
+
+// Scalar returns the only column of the first result row, or `sql.ErrNoRows`.
+func Scalar[T any](ctx context.Context, exe BasicExecutor, query string, params interface{}) (T, error) {
+	var v T
+	rows, err := exe.Rows(ctx, query, params)
+	if err != nil {
+		return v, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		return v, err
+	}
+	err = rows.Rows.Scan(rows.wrapScan(&v))
+	return v, err
+}
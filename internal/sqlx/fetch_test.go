@@ -0,0 +1,91 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type _FetchUser struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+// _WrapDriver scans text into `[]string` by splitting it on commas, like the postgres driver scans arrays.
+type _WrapDriver struct {
+	_TestDriver
+}
+
+type _CommaScanner struct {
+	ptr *[]string
+}
+
+func (s _CommaScanner) Scan(src any) error {
+	*s.ptr = strings.Split(src.(string), ",")
+	return nil
+}
+
+func (_ _WrapDriver) WrapScan(ptr any) any {
+	if v, ok := ptr.(*[]string); ok {
+		return _CommaScanner{ptr: v}
+	}
+	return ptr
+}
+
+func newFetchDB() *DB {
+	db, conn := newFakeDB()
+	db.driver = _WrapDriver{}
+	conn.results["users"] = &_FakeRows{
+		columns: []string{"id", "name"},
+		values:  [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}},
+	}
+	conn.results["empty"] = &_FakeRows{columns: []string{"id"}}
+	conn.results["count"] = &_FakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(2)}}}
+	conn.results["names"] = &_FakeRows{columns: []string{"names"}, values: [][]driver.Value{{"a,b"}}}
+	return db
+}
+
+func TestFetch(t *testing.T) {
+	db := newFetchDB()
+	ctx := context.Background()
+
+	user, err := One[_FetchUser](ctx, db, "users", nil)
+	if err != nil || user != (_FetchUser{Id: 1, Name: "a"}) {
+		t.Fatal(user, err)
+	}
+	ptr, err := One[*_FetchUser](ctx, db, "users", nil)
+	if err != nil || *ptr != (_FetchUser{Id: 1, Name: "a"}) {
+		t.Fatal(ptr, err)
+	}
+	if _, err = One[_FetchUser](ctx, db, "empty", nil); err != sql.ErrNoRows {
+		t.Fatal(err)
+	}
+
+	lst, err := Many[*_FetchUser](ctx, db, "users", nil)
+	if err != nil || len(lst) != 2 || *lst[1] != (_FetchUser{Id: 2, Name: "b"}) {
+		t.Fatal(lst, err)
+	}
+	empty, err := Many[_FetchUser](ctx, db, "empty", nil)
+	if err != nil || len(empty) != 0 {
+		t.Fatal(empty, err)
+	}
+	maps, err := Many[map[string]interface{}](ctx, db, "users", nil)
+	if err != nil || len(maps) != 2 || maps[1]["name"] != "b" {
+		t.Fatal(maps, err)
+	}
+
+	count, err := Scalar[int64](ctx, db, "count", nil)
+	if err != nil || count != 2 {
+		t.Fatal(count, err)
+	}
+	names, err := Scalar[[]string](ctx, db, "names", nil)
+	if err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatal(names, err)
+	}
+	if _, err = Scalar[int64](ctx, db, "empty", nil); err != sql.ErrNoRows {
+		t.Fatal(err)
+	}
+}
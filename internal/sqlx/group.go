@@ -26,6 +26,8 @@ type GroupOptions struct {
 	ConnMaxLifetime     int
 	ConnMaxIdleTime     int
 	Logger              Logger
+	QueryHooks          []QueryHook
+	StmtCacheSize       int // see `DB.EnableStmtCache`
 }
 
 type Group struct {
@@ -73,12 +75,20 @@ func (g *Group) Prepare(ctx context.Context, query string) (*Stmt, error) {
 	return g.w.Prepare(ctx, query)
 }
 
+func (g *Group) BulkInsert(ctx context.Context, tableOrModel any, rows any) (int64, error) {
+	return g.w.BulkInsert(ctx, tableOrModel, rows)
+}
+
+func (g *Group) Upsert(ctx context.Context, model any, opts *UpsertOptions) (int64, error) {
+	return g.w.Upsert(ctx, model, opts)
+}
+
 func (g *Group) DB() *DB {
 	return g.w
 }
 
 var (
-	_ Executor = (*Group)(nil)
+	_ BulkExecutor = (*Group)(nil)
 )
 
 func NewGroup(driver Driver, dsn string, opts *GroupOptions) *Group {
@@ -103,8 +113,18 @@ func (g *Group) SetLogger(logger Logger) *Group {
 	return g
 }
 
+// AddQueryHook adds hooks to all databases of the group, it is not concurrency safe.
+func (g *Group) AddQueryHook(hooks ...QueryHook) *Group {
+	g.opts.QueryHooks = append(g.opts.QueryHooks, hooks...)
+	g.w.AddQueryHook(hooks...)
+	for _, r := range g.rs {
+		r.AddQueryHook(hooks...)
+	}
+	return g
+}
+
 func (g *Group) open(dsn string) (*DB, error) {
-	c, e := g.driver.Open(dsn)
+	c, e := g.driver.Open(g.driver.Dialect().NormalizeDSN(dsn))
 	if e != nil {
 		return nil, e
 	}
@@ -124,7 +144,11 @@ func (g *Group) open(dsn string) (*DB, error) {
 	if g.logger != nil {
 		g.logger.Printf("0.0/internal/sqlx: open database %s", dsn)
 	}
-	return &DB{std: stdDB, logger: g.logger, driver: g.driver}, nil
+	db := &DB{std: stdDB, logger: g.logger, driver: g.driver}
+	db.logQueries()
+	db.AddQueryHook(g.opts.QueryHooks...)
+	db.EnableStmtCache(g.opts.StmtCacheSize)
+	return db, nil
 }
 
 func (g *Group) mustOpen(dsn string) *DB {
@@ -186,31 +210,55 @@ func getExe(ctx context.Context) Executor {
 
 type TxOptions struct {
 	sql.TxOptions
+	// Savepoint is the name of the savepoint used by a nested transaction, generated if empty.
+	// It is quoted by the dialect like other identifiers.
 	Savepoint      string
 	JustWritableDB bool
+	// AbortParentOnError makes the parent transaction unable to commit once this nested transaction is rolled back.
+	AbortParentOnError bool
+	// RawConn begins a top-level transaction by `DB.BeginConnTx`, so that `RawConn` can access its connection.
+	RawConn bool
+
+	// MaxRetries is the max number of retries used by `Group.InTx`, 0 means `DefaultTxMaxRetries`,
+	// a negative value disables retrying.
+	MaxRetries int
 }
 
-func (g *Group) MustBegin(ctx context.Context, opts *TxOptions) (context.Context, *Tx) {
+func (g *Group) Begin(ctx context.Context, opts *TxOptions) (context.Context, *Tx, error) {
 	exe := getExe(ctx)
-	var rTx *Tx
 	if exe != nil {
-		tx, ok := exe.(*Tx)
-		if ok { // options can not be nil
-			rTx = tx.MustBeginTx(ctx, opts.Savepoint)
+		if tx, ok := exe.(*Tx); ok {
+			rTx, err := tx.BeginTx(ctx, opts)
+			if err != nil {
+				return ctx, nil, err
+			}
+			return context.WithValue(ctx, _KeyTx, rTx), rTx, nil
 		}
 	}
-	if rTx == nil { // options can be nil
-		if opts != nil {
-			var db = g.w
-			if opts.ReadOnly && !opts.JustWritableDB {
-				db = g.pickRDB()
-			}
-			rTx = db.MustBeginTx(ctx, &opts.TxOptions)
-		} else {
-			rTx = g.w.MustBeginTx(ctx, nil)
+
+	var db = g.w
+	var txOpts *sql.TxOptions
+	var pinConn bool
+	if opts != nil {
+		if opts.ReadOnly && !opts.JustWritableDB {
+			db = g.pickRDB()
 		}
+		txOpts = &opts.TxOptions
+		pinConn = opts.RawConn
+	}
+	rTx, err := db.begin(ctx, txOpts, pinConn)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return context.WithValue(ctx, _KeyTx, rTx), rTx, nil
+}
+
+func (g *Group) MustBegin(ctx context.Context, opts *TxOptions) (context.Context, *Tx) {
+	ctx, tx, err := g.Begin(ctx, opts)
+	if err != nil {
+		panic(err)
 	}
-	return context.WithValue(ctx, _KeyTx, rTx), rTx
+	return ctx, tx
 }
 
 func (g *Group) ReadonlyDB() *DB {
@@ -0,0 +1,119 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Models can implement these interfaces to be called by struct-based write operations.
+// The `ctx` passed to hooks holds the executor of the operation, see `ExecutorFrom`.
+type (
+	BeforeInsertHook interface {
+		BeforeInsert(ctx context.Context) error
+	}
+	AfterInsertHook interface {
+		AfterInsert(ctx context.Context) error
+	}
+	BeforeUpdateHook interface {
+		BeforeUpdate(ctx context.Context) error
+	}
+	AfterUpdateHook interface {
+		AfterUpdate(ctx context.Context) error
+	}
+	BeforeDeleteHook interface {
+		BeforeDelete(ctx context.Context) error
+	}
+	AfterDeleteHook interface {
+		AfterDelete(ctx context.Context) error
+	}
+)
+
+type Hook func(ctx context.Context, model any) error
+
+var (
+	HookBeforeInsert Hook = func(ctx context.Context, model any) error {
+		if h, ok := model.(BeforeInsertHook); ok {
+			return h.BeforeInsert(ctx)
+		}
+		return nil
+	}
+	HookAfterInsert Hook = func(ctx context.Context, model any) error {
+		if h, ok := model.(AfterInsertHook); ok {
+			return h.AfterInsert(ctx)
+		}
+		return nil
+	}
+	HookBeforeUpdate Hook = func(ctx context.Context, model any) error {
+		if h, ok := model.(BeforeUpdateHook); ok {
+			return h.BeforeUpdate(ctx)
+		}
+		return nil
+	}
+	HookAfterUpdate Hook = func(ctx context.Context, model any) error {
+		if h, ok := model.(AfterUpdateHook); ok {
+			return h.AfterUpdate(ctx)
+		}
+		return nil
+	}
+	HookBeforeDelete Hook = func(ctx context.Context, model any) error {
+		if h, ok := model.(BeforeDeleteHook); ok {
+			return h.BeforeDelete(ctx)
+		}
+		return nil
+	}
+	HookAfterDelete Hook = func(ctx context.Context, model any) error {
+		if h, ok := model.(AfterDeleteHook); ok {
+			return h.AfterDelete(ctx)
+		}
+		return nil
+	}
+)
+
+// ExecutorFrom returns the executor held by `ctx`, nil if not found.
+func ExecutorFrom(ctx context.Context) Executor { return getExe(ctx) }
+
+func withExecutor(ctx context.Context, exe Executor) context.Context {
+	switch v := exe.(type) {
+	case *Tx:
+		return context.WithValue(ctx, _KeyTx, v)
+	case *DB:
+		return context.WithValue(ctx, _KeyDB, v)
+	}
+	return context.WithValue(ctx, _KeyDB, exe.DB())
+}
+
+func callHook(ctx context.Context, hook Hook, mv reflect.Value) error {
+	// hooks are usually implemented by pointer receivers
+	if mv.CanAddr() {
+		return hook(ctx, mv.Addr().Interface())
+	}
+	return hook(ctx, mv.Interface())
+}
+
+// InvokeHook calls `hook` for `models`, a struct, a pointer to struct, or a slice of them.
+func InvokeHook(ctx context.Context, exe Executor, hook Hook, models any) error {
+	ctx = withExecutor(ctx, exe)
+	rv := reflect.ValueOf(models)
+	if rv.Kind() != reflect.Slice {
+		mv, err := modelValue(models)
+		if err != nil {
+			return err
+		}
+		return callHook(ctx, hook, mv)
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		mv := rv.Index(i)
+		if mv.Kind() == reflect.Ptr {
+			if mv.IsNil() {
+				return fmt.Errorf("0.0/internal/sqlx: nil row at %d", i)
+			}
+			mv = mv.Elem()
+		}
+		if err := callHook(ctx, hook, mv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
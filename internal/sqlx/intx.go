@@ -0,0 +1,108 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultTxMaxRetries = 5
+
+	txRetryMinBackoff = time.Millisecond * 10
+	txRetryMaxBackoff = time.Second
+)
+
+// retryableSQLStates serialization_failure and deadlock_detected
+var retryableSQLStates = map[string]bool{
+	"40001": true,
+	"40P01": true,
+}
+
+type sqlStateError interface {
+	SQLState() string
+}
+
+// IsRetryableTxError reports whether the whole transaction can be retried after `err`.
+func IsRetryableTxError(err error) bool {
+	var se sqlStateError
+	if errors.As(err, &se) {
+		return retryableSQLStates[se.SQLState()]
+	}
+	return false
+}
+
+func txRetryBackoff(attempt int) time.Duration {
+	d := txRetryMinBackoff << attempt
+	if d <= 0 || d > txRetryMaxBackoff {
+		d = txRetryMaxBackoff
+	}
+	// full jitter
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// InTx runs `fn` in a transaction; the transaction is committed if `fn` returns nil, otherwise rolled back.
+// If `ctx` already holds a transaction, a nested transaction is created via savepoint.
+// A top-level transaction failed with a serialization failure or a deadlock will be retried.
+func (g *Group) InTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	_, nested := getExe(ctx).(*Tx)
+
+	maxRetries := DefaultTxMaxRetries
+	if opts != nil && opts.MaxRetries != 0 {
+		maxRetries = opts.MaxRetries
+	}
+	if nested || maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := g.runInTx(ctx, opts, fn)
+		if err == nil || attempt >= maxRetries || !IsRetryableTxError(err) {
+			return err
+		}
+
+		backoff := txRetryBackoff(attempt)
+		if g.logger != nil {
+			g.logger.Printf("0.0/internal/sqlx: tx retry, attempt %d after %s, %s", attempt+1, backoff, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (g *Group) runInTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	ctx, tx, err := g.Begin(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		v := recover()
+		// a failed top-level commit has already finished the transaction
+		if v == nil && (err == nil || tx.done) {
+			return
+		}
+		if re := tx.Rollback(); re != nil && g.logger != nil {
+			g.logger.Printf("0.0/internal/sqlx: rollback error: %s", re)
+		}
+		if v != nil {
+			panic(v)
+		}
+	}()
+
+	if err = fn(ctx, tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("0.0/internal/sqlx: commit failed, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package sqlx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type _SQLStateError string
+
+func (e _SQLStateError) Error() string    { return string(e) }
+func (e _SQLStateError) SQLState() string { return string(e) }
+
+func TestIsRetryableTxError(t *testing.T) {
+	if !IsRetryableTxError(_SQLStateError("40001")) {
+		t.Fail()
+	}
+	if !IsRetryableTxError(fmt.Errorf("wrapped: %w", _SQLStateError("40P01"))) {
+		t.Fail()
+	}
+	if IsRetryableTxError(_SQLStateError("23505")) || IsRetryableTxError(errors.New("40001")) {
+		t.Fail()
+	}
+}
+
+func TestTxRetryBackoff(t *testing.T) {
+	for i := 0; i < 64; i++ {
+		d := txRetryBackoff(i)
+		if d <= 0 || d > txRetryMaxBackoff {
+			t.Fatalf("bad backoff %s at %d", d, i)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package sqlx
+
+import (
+	"context"
+)
+
+// Iterator scans rows into `T` one by one, the scan buffers are reused between rows.
+// If `T` is a pointer or a map, the value returned by `Value` is also reused, copy it if you need to keep it.
+type Iterator[T any] struct {
+	rows    *Rows
+	columns []string
+	temp    []interface{}
+	dist    interface{}
+	value   *T
+	err     error
+}
+
+// Iterate executes the query and returns an iterator of the result rows, the iterator must be closed.
+func Iterate[T any](ctx context.Context, exe BasicExecutor, query string, params interface{}) (*Iterator[T], error) {
+	rows, err := exe.Rows(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+
+	it := &Iterator[T]{rows: rows, columns: columns, temp: make([]interface{}, 0, len(columns))}
+	it.value, it.dist = newDist[T]()
+	return it, nil
+}
+
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	if err := it.rows.doScan(it.dist, it.columns, &it.temp); err != nil {
+		it.err = err
+		return false
+	}
+	return true
+}
+
+func (it *Iterator[T]) Value() T { return *it.value }
+
+func (it *Iterator[T]) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+func (it *Iterator[T]) Close() error { return it.rows.Close() }
+
+// Each calls `fn` for every result row, stops at the first error returned by `fn`.
+func Each[T any](ctx context.Context, exe BasicExecutor, query string, params interface{}, fn func(T) error) error {
+	it, err := Iterate[T](ctx, exe, query, params)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		if err = fn(it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
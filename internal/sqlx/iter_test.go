@@ -0,0 +1,90 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// connReleased reports whether the only connection of `db` is back to the pool, i.e. rows are closed.
+func connReleased(db *DB) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+	_, err := Scalar[int64](ctx, db, "count", nil)
+	return err == nil
+}
+
+func TestIterate(t *testing.T) {
+	db := newFetchDB()
+	ctx := context.Background()
+
+	it, err := Iterate[_FetchUser](ctx, db, "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for it.Next() {
+		ids = append(ids, it.Value().Id)
+	}
+	if err = it.Err(); err != nil || !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Fatal(ids, err)
+	}
+	if err = it.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// breaking early, the rows are released by `Close`
+	it, err = Iterate[_FetchUser](ctx, db, "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !it.Next() || it.Value().Id != 1 {
+		t.Fatal(it.Value())
+	}
+	if err = it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !connReleased(db) {
+		t.Fatal("rows are not closed")
+	}
+	if it.Next() {
+		t.Fatal("next after close")
+	}
+}
+
+func TestEach(t *testing.T) {
+	db := newFetchDB()
+	ctx := context.Background()
+
+	var names []string
+	stop := errors.New("stop")
+	err := Each[*_FetchUser](ctx, db, "users", nil, func(u *_FetchUser) error {
+		names = append(names, u.Name)
+		return stop
+	})
+	if err != stop || !reflect.DeepEqual(names, []string{"a"}) {
+		t.Fatal(names, err)
+	}
+	if !connReleased(db) {
+		t.Fatal("rows are not closed after the callback failed")
+	}
+
+	names = names[:0]
+	if err = Each[_FetchUser](ctx, db, "users", nil, func(u _FetchUser) error {
+		names = append(names, u.Name)
+		return nil
+	}); err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatal(names, err)
+	}
+
+	// scan errors stop the iteration
+	fdb, conn := newFakeDB()
+	conn.results["bad"] = &_FakeRows{columns: []string{"id"}, values: [][]driver.Value{{"x"}}}
+	called := false
+	if err = Each[_FetchUser](ctx, fdb, "bad", nil, func(_FetchUser) error { called = true; return nil }); err == nil || called {
+		t.Fatal(err, called)
+	}
+}
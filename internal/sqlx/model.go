@@ -0,0 +1,183 @@
+package sqlx
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/zzztttkkk/0.0/internal/utils"
+	"reflect"
+	"strings"
+	"time"
+)
+
+func tableNameOf(val reflect.Value) string {
+	var tablename string
+	if tableNameFn := val.MethodByName("TableName"); tableNameFn.IsValid() {
+		if fn, _ := tableNameFn.Interface().(func() string); fn != nil {
+			tablename = fn()
+		}
+	}
+	if len(tablename) < 1 {
+		tablename = strings.ToLower(val.Type().Name())
+	}
+	return tablename
+}
+
+// modelFields returns the fields mapped to table columns, in declaration order.
+func modelFields(t reflect.Type) []*utils.FieldInfo {
+	var fields []*utils.FieldInfo
+	for _, info := range DBReflectMapper.TypeMap(t).Index {
+		if info.Path != info.Name || info.Embedded {
+			continue
+		}
+		fields = append(fields, info)
+	}
+	return fields
+}
+
+// modelValue returns the struct value of `v`, which should be a struct or a pointer to struct.
+func modelValue(v any) (reflect.Value, error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return val, fmt.Errorf("0.0/internal/sqlx: nil model `%T`", v)
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return val, fmt.Errorf("0.0/internal/sqlx: `%T` is not a struct", v)
+	}
+	return val, nil
+}
+
+// isGeneratedField reports whether the column value can be generated by the database,
+// so a zero value should not be written.
+func isGeneratedField(info *utils.FieldInfo) bool {
+	if isComputedField(info) {
+		return true
+	}
+	if _, ok := info.Options["incr"]; ok {
+		return true
+	}
+	_, ok := info.Options["default"]
+	return ok
+}
+
+// isComputedField reports whether the column is always computed by the database, e.g. a `fts` column,
+// so it is never written.
+func isComputedField(info *utils.FieldInfo) bool {
+	_, ok := info.Options["fts"]
+	return ok
+}
+
+// insertFields returns the fields should be written when inserting `items`,
+// generated fields are skipped if they are zero in all items, computed fields are always skipped.
+func insertFields(t reflect.Type, items []reflect.Value) []*utils.FieldInfo {
+	var fields []*utils.FieldInfo
+	for _, info := range modelFields(t) {
+		if isComputedField(info) {
+			continue
+		}
+		if isGeneratedField(info) {
+			allZero := true
+			for _, item := range items {
+				if !utils.FieldByIndexesReadOnly(item, info.Index).IsZero() {
+					allZero = false
+					break
+				}
+			}
+			if allZero {
+				continue
+			}
+		}
+		fields = append(fields, info)
+	}
+	return fields
+}
+
+// optionField returns the first field with the tag option `opt`.
+func optionField(t reflect.Type, opt string) *utils.FieldInfo {
+	for _, info := range modelFields(t) {
+		if _, ok := info.Options[opt]; ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// softDeleteField returns the field tagged with `softdelete`, or nil if the model does not support soft delete.
+// A `time.Time` field panics, it has no value meaning not deleted, use `*time.Time` or `sql.NullTime` instead.
+func softDeleteField(t reflect.Type) *utils.FieldInfo {
+	info := optionField(t, "softdelete")
+	if info != nil && info.Field.Type == BuiltinTimeType {
+		panic(fmt.Errorf("0.0/internal/sqlx: soft delete field `%s` of `%s` should be `*time.Time` or `sql.NullTime`", info.Name, t))
+	}
+	return info
+}
+
+// versionField returns the field tagged with `version`, which is used for optimistic locking.
+func versionField(t reflect.Type) *utils.FieldInfo { return optionField(t, "version") }
+
+func nextVersion(v reflect.Value) (reflect.Value, error) {
+	nv := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		nv.SetInt(v.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		nv.SetUint(v.Uint() + 1)
+	default:
+		return nv, fmt.Errorf("0.0/internal/sqlx: unsupported version field type `%s`", v.Type())
+	}
+	return nv, nil
+}
+
+func isNullableField(info *utils.FieldInfo) bool {
+	if _, ok := info.Options["nullable"]; ok {
+		return true
+	}
+	t := info.Field.Type
+	return t.Kind() == reflect.Ptr || BuiltinSqlTypes[t] != nil
+}
+
+// aliveCondition returns the condition of rows not soft deleted, a nullable field is NULL, otherwise zero.
+func aliveCondition(quote func(string) string, info *utils.FieldInfo) string {
+	if isNullableField(info) {
+		return fmt.Sprintf("%s IS NULL", quote(info.Name))
+	}
+	return fmt.Sprintf("%s = 0", quote(info.Name))
+}
+
+// quoteJoin quotes `names` by `quote` and joins them by commas.
+func quoteJoin(quote func(string) string, names []string) string {
+	return strings.Join(utils.SliceMap(names, func(_ int, n string) string { return quote(n) }), ", ")
+}
+
+// softDeleteValue returns the value of `t` marking a row deleted at `now`, integers are unix milliseconds.
+func softDeleteValue(t reflect.Type, now time.Time) (reflect.Value, error) {
+	switch t {
+	case BuiltinTimeType:
+		return reflect.ValueOf(now), nil
+	case reflect.TypeOf(sql.NullTime{}):
+		return reflect.ValueOf(sql.NullTime{Time: now, Valid: true}), nil
+	case reflect.TypeOf(sql.NullInt64{}):
+		return reflect.ValueOf(sql.NullInt64{Int64: now.UnixMilli(), Valid: true}), nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Ptr:
+		ev, err := softDeleteValue(t.Elem(), now)
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(ev)
+		return v, nil
+	case reflect.Int, reflect.Int64:
+		v.SetInt(now.UnixMilli())
+		return v, nil
+	case reflect.Uint, reflect.Uint64:
+		v.SetUint(uint64(now.UnixMilli()))
+		return v, nil
+	}
+	return v, fmt.Errorf("0.0/internal/sqlx: unsupported soft delete field type `%s`", t)
+}
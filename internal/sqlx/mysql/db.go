@@ -0,0 +1,17 @@
+package mysql
+
+import (
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type DB struct {
+	*sqlx.DB
+}
+
+func Open(dsn string, readonly bool, logger sqlx.Logger) *DB {
+	db, err := sqlx.OpenDB(&Driver{}, dsn, readonly, logger)
+	if err != nil {
+		panic(err)
+	}
+	return &DB{db}
+}
@@ -0,0 +1,208 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/utils"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (_ *Driver) DDL(info *utils.FieldInfo) *sqlx.FieldDefinition {
+	var fd = &sqlx.FieldDefinition{}
+
+	fd.SqlType = mysqlType(info.Field.Type, info.Options, fd)
+
+	if _, incr := info.Options["incr"]; incr {
+		fd.SqlType += " AUTO_INCREMENT"
+	}
+	return fd
+}
+
+// CreateIndex returns `CREATE INDEX` without `IF NOT EXISTS`, which mysql does not support.
+// Mysql has no partial indexes, the `WHERE` of the index is dropped, so soft deleted rows still occupy
+// values of unique indexes.
+func (d Dialect) CreateIndex(tablename, name string, info *sqlx.IndexInfo) string {
+	if len(info.Include) > 0 {
+		panic(fmt.Errorf("0.0/internal/sqlx/mysql: index `%s`, `INCLUDE` is not supported", name))
+	}
+
+	var sb strings.Builder
+	sort.Slice(info.Fields, func(i, j int) bool { return info.Fields[i].SortInIndex < info.Fields[j].SortInIndex })
+
+	sb.WriteString("CREATE ")
+	if strings.HasSuffix(name, "unique") {
+		sb.WriteString("UNIQUE ")
+	}
+	sb.WriteString("INDEX ")
+	sb.WriteString(d.Quote(name))
+	switch strings.ToLower(info.Using) {
+	case "":
+	case "btree", "hash":
+		sb.WriteString(" USING ")
+		sb.WriteString(strings.ToUpper(info.Using))
+	default:
+		panic(fmt.Errorf("0.0/internal/sqlx/mysql: index `%s`, unsupported method `%s`", name, info.Using))
+	}
+	sb.WriteString(" ON ")
+	sb.WriteString(d.Quote(tablename))
+	sb.WriteString(" (")
+	for i, f := range info.Fields {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(d.Quote(f.FieldName))
+		if f.OrderType == sqlx.IndexFieldOrderAsc {
+			sb.WriteString(" ASC")
+		} else {
+			sb.WriteString(" DESC")
+		}
+	}
+	sb.WriteString(");")
+	return sb.String()
+}
+
+func (_ Dialect) IndexExists(ctx context.Context, exe sqlx.Executor, tablename, name string) (bool, error) {
+	count, err := sqlx.Scalar[int64](
+		ctx, exe,
+		`SELECT count(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ${table} AND index_name = ${name}`,
+		sqlx.Params{"table": tablename, "name": name},
+	)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func getLength(v string) (int, bool) {
+	if v[0] == '~' {
+		n, e := strconv.ParseUint(v[1:], 10, 32)
+		if e != nil {
+			panic(fmt.Errorf("bad field length: `%s`", v))
+		}
+		return int(n), false
+	}
+	n, e := strconv.ParseUint(v, 10, 32)
+	if e != nil {
+		panic(fmt.Errorf("bad field length: `%s`", v))
+	}
+	return int(n), true
+}
+
+var (
+	_UuidType    = reflect.TypeOf((*pgtype.UUID)(nil)).Elem()
+	_DateType    = reflect.TypeOf((*pgtype.Date)(nil)).Elem()
+	_TimeType    = reflect.TypeOf((*time.Time)(nil)).Elem()
+	_RawJSONType = reflect.TypeOf((*json.RawMessage)(nil)).Elem()
+	_NullTypes   = make(map[reflect.Type]reflect.Type)
+)
+
+func init() {
+	addToMap := func(a, b any) {
+		_NullTypes[reflect.TypeOf(a)] = reflect.TypeOf(b)
+	}
+	addToMap(sql.NullString{}, "")
+	addToMap(sql.NullBool{}, false)
+	addToMap(sql.NullFloat64{}, float64(0))
+	addToMap(sql.NullInt16{}, int16(0))
+	addToMap(sql.NullInt32{}, int32(0))
+	addToMap(sql.NullInt64{}, int64(0))
+	addToMap(sql.NullTime{}, time.Now())
+	addToMap(sql.NullByte{}, uint8(0))
+}
+
+func mysqlType(t reflect.Type, opts map[string]string, fd *sqlx.FieldDefinition) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Ptr {
+		panic(errors.New(`bad field type, always pointer`))
+	}
+
+	userType := strings.TrimSpace(opts["sqltype"])
+	if len(userType) > 0 {
+		return userType
+	}
+
+	switch t {
+	case _UuidType:
+		return "CHAR(36)"
+	case _TimeType:
+		return "DATETIME(6)"
+	case _DateType:
+		return "DATE"
+	case _RawJSONType:
+		return "JSON"
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "BIGINT"
+	case reflect.Int8:
+		return "TINYINT"
+	case reflect.Int16:
+		return "SMALLINT"
+	case reflect.Int32:
+		return "INT"
+	case reflect.Uint, reflect.Uint64:
+		return "BIGINT UNSIGNED"
+	case reflect.Uint8:
+		return "TINYINT UNSIGNED"
+	case reflect.Uint16:
+		return "SMALLINT UNSIGNED"
+	case reflect.Uint32:
+		return "INT UNSIGNED"
+	case reflect.String:
+		{
+			lengthOV := opts["length"]
+			if len(lengthOV) < 1 {
+				return "TEXT"
+			}
+
+			length, isFixed := getLength(lengthOV)
+			if length < 1 || length > 6555 {
+				return "TEXT"
+			}
+			if isFixed && length < 256 {
+				return fmt.Sprintf("CHAR(%d)", length)
+			}
+			return fmt.Sprintf("VARCHAR(%d)", length)
+		}
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Float32:
+		return "FLOAT"
+	case reflect.Float64:
+		return "DOUBLE"
+	case reflect.Slice:
+		{
+			if t.Elem().Kind() == reflect.Uint8 {
+				return "BLOB"
+			}
+			panic(fmt.Errorf("mysql does not support arrays, `%s`, use `sqltype` with a `driver.Valuer`", t))
+		}
+	case reflect.Map:
+		// needs a `driver.Valuer` and a `sql.Scanner`
+		return "JSON"
+	case reflect.Struct:
+		{
+			realType := _NullTypes[t]
+			if realType != nil {
+				if fd != nil {
+					fd.Nullable = true
+				}
+				return mysqlType(realType, opts, fd)
+			}
+		}
+	}
+	panic(fmt.Errorf("unexpect field type, %s.%s", t.PkgPath(), t.Name()))
+}
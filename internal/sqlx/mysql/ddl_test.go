@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _Account struct {
+	Order     int64           `db:"order"`
+	Id        uint64          `db:"id;primary;incr"`
+	Email     string          `db:"email;length=~64;index=account_email_unique,asc"`
+	Code      string          `db:"code;length=6"`
+	Bio       sql.NullString  `db:"bio"`
+	Ext       json.RawMessage `db:"ext"`
+	CreatedAt time.Time       `db:"created_at;index=account_created_at"`
+	DeletedAt int64           `db:"deleted_at;default=0;softdelete"`
+}
+
+func TestDDL(t *testing.T) {
+	ddl, indexes := sqlx.TableDDL(&Driver{}, _Account{})
+	for _, v := range []string{
+		"CREATE TABLE IF NOT EXISTS _account (",
+		"id BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,",
+		"`order` BIGINT NOT NULL,",
+		"email VARCHAR(64) NOT NULL,",
+		"code CHAR(6) NOT NULL,",
+		"bio TEXT,",
+		"ext JSON NOT NULL,",
+		"created_at DATETIME(6) NOT NULL,",
+		"deleted_at BIGINT NOT NULL DEFAULT 0,",
+	} {
+		if !strings.Contains(ddl, v) {
+			t.Fatalf("%q not in %s", v, ddl)
+		}
+	}
+
+	expected := []string{
+		"CREATE INDEX account_created_at ON _account (created_at DESC);",
+		"CREATE UNIQUE INDEX account_email_unique ON _account (email ASC);",
+	}
+	if strings.Join(indexes, "\n") != strings.Join(expected, "\n") {
+		t.Fatal(indexes)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	d := Dialect{}
+	v := d.Upsert("user", &sqlx.UpsertClause{Target: []string{"email"}, Where: "deleted_at = 0", Updates: []string{"name", "group"}, Version: "version"})
+	if v != "ON DUPLICATE KEY UPDATE name = VALUES(name), `group` = VALUES(`group`), version = version + 1" {
+		t.Fatal(v)
+	}
+	if v = d.Upsert("user", &sqlx.UpsertClause{Target: []string{"email"}}); v != "ON DUPLICATE KEY UPDATE email = email" {
+		t.Fatal(v)
+	}
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type Driver struct{}
+
+// Open parses a go-sql-driver dsn, `user:password@tcp(host:port)/dbname?param=value`.
+// `parseTime` is enabled unless it is set in the dsn, so that `DATETIME` columns can be scanned into `time.Time`.
+func (_ *Driver) Open(dsn string) (driver.Connector, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(dsn, "parseTime=") {
+		cfg.ParseTime = true
+	}
+	return mysql.NewConnector(cfg)
+}
+
+func (_ *Driver) Placeholder(_ int, _ string) string { return "?" }
+
+// MaxParams is the max count of placeholders of a prepared statement.
+func (_ *Driver) MaxParams() int { return 65535 }
+
+func (_ *Driver) Dialect() sqlx.Dialect { return Dialect{} }
+
+type Dialect struct {
+	sqlx.DefaultDialect
+}
+
+func (_ Dialect) Quote(ident string) string { return sqlx.QuoteIdent(ident, '`') }
+
+// NormalizeDSN removes the `mysql://` prefix, which go-sql-driver does not accept.
+func (_ Dialect) NormalizeDSN(dsn string) string {
+	return strings.TrimPrefix(strings.TrimSpace(dsn), "mysql://")
+}
+
+func (_ Dialect) Returning() bool { return false }
+
+func (d Dialect) DropTable(tablename string) string {
+	return fmt.Sprintf("DROP TABLE %s", d.Quote(tablename))
+}
+
+func (d Dialect) AddColumn(tablename, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.Quote(tablename), column)
+}
+
+func (d Dialect) CreateTable(table *sqlx.TableDefinition) string {
+	return sqlx.CreateTableStmt(d.Quote, table)
+}
+
+// Upsert renders `ON DUPLICATE KEY UPDATE c = VALUES(c)`, which is triggered by any unique index, so the conflict
+// target and the condition of partial indexes are ignored. `c = c` of the first target column keeps the row unchanged.
+func (d Dialect) Upsert(_ string, clause *sqlx.UpsertClause) string {
+	if len(clause.Updates) < 1 {
+		c := d.Quote(clause.Target[0])
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", c, c)
+	}
+	sets := make([]string, 0, len(clause.Updates)+1)
+	for _, c := range clause.Updates {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", d.Quote(c), d.Quote(c)))
+	}
+	if len(clause.Version) > 0 {
+		sets = append(sets, fmt.Sprintf("%s = %s + 1", d.Quote(clause.Version), d.Quote(clause.Version)))
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+var (
+	_ sqlx.Driver          = (*Driver)(nil)
+	_ sqlx.MaxParamsDriver = (*Driver)(nil)
+	_ sqlx.Dialect         = Dialect{}
+	_ sqlx.IndexChecker    = Dialect{}
+)
@@ -0,0 +1,268 @@
+package sqlx
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zzztttkkk/0.0/internal/utils"
+	"reflect"
+	"strings"
+)
+
+type SortKey struct {
+	// Column is a result column of the base query, also the `db` name of the field in result rows.
+	Column string
+	Desc   bool
+}
+
+type PageQuery struct {
+	// Query is the base query without `ORDER BY` and `LIMIT`, it is wrapped as a subquery.
+	Query string
+	// Params are params of the base query, a `Params`, a `map[string]interface{}` or a struct.
+	Params interface{}
+	// Keys should make a total order, the last one is usually the primary key; keyset pagination requires
+	// them not null.
+	Keys  []SortKey
+	Limit int
+}
+
+type KeysetPage[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+type OffsetPage[T any] struct {
+	Items    []T   `json:"items"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+	Total    int64 `json:"total"` // -1 if not counted
+}
+
+var ErrBadCursor = errors.New("0.0/internal/sqlx: bad cursor")
+
+const pageParamPrefix = "_page_"
+
+type _Cursor struct {
+	Prev   bool              `json:"p,omitempty"`
+	Values []json.RawMessage `json:"v"`
+}
+
+func (q *PageQuery) check() error {
+	if len(q.Keys) < 1 {
+		return errors.New("0.0/internal/sqlx: empty sort keys")
+	}
+	if q.Limit < 1 {
+		return fmt.Errorf("0.0/internal/sqlx: bad page limit, %d", q.Limit)
+	}
+	return nil
+}
+
+func (q *PageQuery) params() (Params, error) {
+	m, err := paramsToMap(q.Params)
+	if err != nil {
+		return nil, err
+	}
+	params := make(Params, len(m)+len(q.Keys)+2)
+	for k, v := range m {
+		params[k] = v
+	}
+	return params, nil
+}
+
+func (q *PageQuery) orderBy(reverse bool) string {
+	return strings.Join(utils.SliceMap(q.Keys, func(_ int, k SortKey) string {
+		if k.Desc != reverse {
+			return k.Column + " DESC"
+		}
+		return k.Column + " ASC"
+	}), ", ")
+}
+
+// keysetCondition returns `(k0 > v0) OR (k0 = v0 AND k1 > v1) ...`, operators are flipped for desc keys or `reverse`.
+func (q *PageQuery) keysetCondition(reverse bool) string {
+	var ors []string
+	for i, key := range q.Keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = ${%sk%d}", q.Keys[j].Column, pageParamPrefix, j))
+		}
+		op := ">"
+		if key.Desc != reverse {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ${%sk%d}", key.Column, op, pageParamPrefix, i))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR ")
+}
+
+func keyFieldOf(t reflect.Type, column string) (*utils.FieldInfo, error) {
+	fi := DBReflectMapper.TypeMap(t).Names[column]
+	if fi == nil {
+		return nil, fmt.Errorf("0.0/internal/sqlx: sort key `%s` is not a field of `%s`", column, t)
+	}
+	return fi, nil
+}
+
+func structTypeOf[T any]() (reflect.Type, error) {
+	t := utils.Deref(reflect.TypeOf((*T)(nil)).Elem())
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("0.0/internal/sqlx: `%s` is not a struct", t)
+	}
+	return t, nil
+}
+
+func encodeCursor[T any](q *PageQuery, item T, prev bool) (string, error) {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	c := _Cursor{Prev: prev}
+	for _, key := range q.Keys {
+		fi, err := keyFieldOf(v.Type(), key.Column)
+		if err != nil {
+			return "", err
+		}
+		raw, err := json.Marshal(utils.FieldByIndexesReadOnly(v, fi.Index).Interface())
+		if err != nil {
+			return "", err
+		}
+		c.Values = append(c.Values, raw)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes the cursor and puts key values into `params`, the values are decoded as field types of `t`.
+func decodeCursor(q *PageQuery, t reflect.Type, cursor string, params Params) (bool, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return false, ErrBadCursor
+	}
+	var c _Cursor
+	if err = json.Unmarshal(data, &c); err != nil || len(c.Values) != len(q.Keys) {
+		return false, ErrBadCursor
+	}
+	for i, key := range q.Keys {
+		fi, err := keyFieldOf(t, key.Column)
+		if err != nil {
+			return false, err
+		}
+		ptr := reflect.New(fi.Field.Type)
+		if err = json.Unmarshal(c.Values[i], ptr.Interface()); err != nil {
+			return false, ErrBadCursor
+		}
+		params[fmt.Sprintf("%sk%d", pageParamPrefix, i)] = ptr.Elem().Interface()
+	}
+	return c.Prev, nil
+}
+
+// Keyset returns the page after or before the `cursor`, or the first page if `cursor` is empty.
+// `Next` or `Prev` of the result is empty if there is no more rows in that direction.
+func Keyset[T any](ctx context.Context, exe BasicExecutor, q PageQuery, cursor string) (*KeysetPage[T], error) {
+	if err := q.check(); err != nil {
+		return nil, err
+	}
+	t, err := structTypeOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	params, err := q.params()
+	if err != nil {
+		return nil, err
+	}
+
+	var prev bool
+	var sb strings.Builder
+	sb.WriteString("SELECT * FROM (")
+	sb.WriteString(q.Query)
+	sb.WriteString(") AS _page")
+	if len(cursor) > 0 {
+		if prev, err = decodeCursor(&q, t, cursor, params); err != nil {
+			return nil, err
+		}
+		sb.WriteString(" WHERE ")
+		sb.WriteString(q.keysetCondition(prev))
+	}
+	sb.WriteString(" ORDER BY ")
+	sb.WriteString(q.orderBy(prev))
+	sb.WriteString(fmt.Sprintf(" LIMIT ${%slimit}", pageParamPrefix))
+	params[pageParamPrefix+"limit"] = q.Limit + 1
+
+	items, err := Many[T](ctx, exe, sb.String(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(items) > q.Limit
+	if more {
+		items = items[:q.Limit]
+	}
+	if prev {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &KeysetPage[T]{Items: items}
+	if len(items) < 1 {
+		page.Items = []T{}
+		return page, nil
+	}
+	// moving forward, there are more rows after this page if `more`, and rows before it if we came from a cursor;
+	// moving backward, the opposite.
+	hasNext, hasPrev := more, len(cursor) > 0
+	if prev {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		if page.Next, err = encodeCursor(&q, items[len(items)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = encodeCursor(&q, items[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// Offset returns the page `page`(starts from 1), the total count of rows is queried if `withTotal` is true.
+func Offset[T any](ctx context.Context, exe BasicExecutor, q PageQuery, page int, withTotal bool) (*OffsetPage[T], error) {
+	if err := q.check(); err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	params, err := q.params()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OffsetPage[T]{Page: page, PageSize: q.Limit, Total: -1}
+	if withTotal {
+		result.Total, err = Scalar[int64](ctx, exe, fmt.Sprintf("SELECT count(*) FROM (%s) AS _page", q.Query), params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	params[pageParamPrefix+"limit"] = q.Limit
+	params[pageParamPrefix+"offset"] = (page - 1) * q.Limit
+	query := fmt.Sprintf(
+		"SELECT * FROM (%s) AS _page ORDER BY %s LIMIT ${%slimit} OFFSET ${%soffset}",
+		q.Query, q.orderBy(false), pageParamPrefix, pageParamPrefix,
+	)
+	if result.Items, err = Many[T](ctx, exe, query, params); err != nil {
+		return nil, err
+	}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	return result, nil
+}
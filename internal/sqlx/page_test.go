@@ -0,0 +1,40 @@
+package sqlx
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type _PageItem struct {
+	Id        int64     `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func TestKeysetCursor(t *testing.T) {
+	q := PageQuery{Keys: []SortKey{{Column: "created_at", Desc: true}, {Column: "id"}}, Limit: 10}
+	if v := q.keysetCondition(false); v != "(created_at < ${_page_k0}) OR (created_at = ${_page_k0} AND id > ${_page_k1})" {
+		t.Fatal(v)
+	}
+	if v := q.orderBy(true); v != "created_at ASC, id DESC" {
+		t.Fatal(v)
+	}
+
+	item := &_PageItem{Id: 1 << 60, CreatedAt: time.Date(2022, 11, 15, 1, 2, 3, 4, time.UTC)}
+	cursor, err := encodeCursor(&q, item, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := Params{}
+	prev, err := decodeCursor(&q, reflect.TypeOf(_PageItem{}), cursor, params)
+	if err != nil || !prev {
+		t.Fatal(err)
+	}
+	if params["_page_k1"] != item.Id || !params["_page_k0"].(time.Time).Equal(item.CreatedAt) {
+		t.Fatalf("%v", params)
+	}
+
+	if _, err = decodeCursor(&q, reflect.TypeOf(_PageItem{}), "xx", params); err != ErrBadCursor {
+		t.Fail()
+	}
+}
@@ -1,6 +1,8 @@
 package sqlx
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"github.com/zzztttkkk/0.0/internal/utils"
 	"reflect"
@@ -8,9 +10,45 @@ import (
 )
 
 type _Param struct {
-	name  string
-	begin int
-	end   int
+	name   string
+	begin  int
+	end    int
+	expand bool // `${name...}`
+	inList bool // `IN (${name})`
+}
+
+const expandSuffix = "..."
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// isInList reports whether the param at `q[begin:end+1]` is the only item of an `IN (...)` list.
+func isInList(q []byte, begin, end int) bool {
+	i := begin - 1
+	for i >= 0 && isSpaceByte(q[i]) {
+		i--
+	}
+	if i < 0 || q[i] != '(' {
+		return false
+	}
+	i--
+	for i >= 0 && isSpaceByte(q[i]) {
+		i--
+	}
+	if i < 1 || (q[i] != 'n' && q[i] != 'N') || (q[i-1] != 'i' && q[i-1] != 'I') || (i > 1 && isIdentByte(q[i-2])) {
+		return false
+	}
+
+	j := end + 1
+	for j < len(q) && isSpaceByte(q[j]) {
+		j++
+	}
+	return j < len(q) && q[j] == ')'
 }
 
 func scanParams(q []byte) []_Param {
@@ -49,7 +87,13 @@ func scanParams(q []byte) []_Param {
 		case 1:
 			if r == '}' {
 				status = -1
-				lst = append(lst, _Param{name: string(buf), begin: begin, end: idx})
+				param := _Param{name: string(buf), begin: begin, end: idx}
+				if strings.HasSuffix(param.name, expandSuffix) {
+					param.name = param.name[:len(param.name)-len(expandSuffix)]
+					param.expand = true
+				}
+				param.inList = isInList(q, begin, idx)
+				lst = append(lst, param)
 				buf = buf[:0]
 			} else {
 				buf = append(buf, r)
@@ -60,6 +104,7 @@ func scanParams(q []byte) []_Param {
 	return lst
 }
 
+// ScanParams replaces `${name}` in `txt` with placeholders of `driver`, `${name...}` is treated as `${name}`.
 func ScanParams(txt string, driver Driver) (string, []string) {
 	if !strings.Contains(txt, "${") {
 		return txt, nil
@@ -75,20 +120,125 @@ func ScanParams(txt string, driver Driver) (string, []string) {
 	var keys []string
 	cur := 0
 	for idx, param := range lst {
-		for cur < param.begin {
-			buf.WriteByte(q[cur])
-			cur++
-		}
+		buf.Write(q[cur:param.begin])
 		buf.WriteString(driver.Placeholder(idx, param.name))
 		keys = append(keys, param.name)
 		cur = param.end + 1
 	}
+	buf.Write(q[cur:])
+	return buf.String(), keys
+}
+
+var (
+	ErrBadExpandParam = errors.New("0.0/internal/sqlx: expanded param value should be a slice or an array")
+	ErrExpandInStmt   = errors.New("0.0/internal/sqlx: `${name...}` can not be used in a prepared statement")
+	// ErrEmptyExpandParam is returned for an empty slice expanded outside `IN (...)`, which would change the count
+	// of values or arguments silently.
+	ErrEmptyExpandParam = errors.New("0.0/internal/sqlx: empty expanded param outside `IN (...)`")
+)
+
+func hasExpandParams(txt string) bool {
+	if !strings.Contains(txt, expandSuffix+"}") {
+		return false
+	}
+	for _, param := range scanParams(utils.B(txt)) {
+		if param.expand {
+			return true
+		}
+	}
+	return false
+}
+
+// emptyInList is a subquery that returns no rows, so `IN` matches nothing and `NOT IN` matches everything.
+const emptyInList = "SELECT NULL WHERE 1=0"
 
-	for cur < len(q) {
-		buf.WriteByte(q[cur])
-		cur++
+func isExpandableValue(v interface{}) bool {
+	if v == nil {
+		return false
 	}
-	return buf.String(), keys
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Array:
+		return true
+	}
+	return false
+}
+
+// BindParams likes `ScanParams`, but also expands slice values into placeholder lists.
+//
+// A param is expanded if it is written as `${name...}`, or if it is the only item of an `IN (...)` list and
+// its value is a slice (`[]byte` and `driver.Valuer` are not). An empty slice becomes an empty subquery in an
+// `IN (...)` list, and is `ErrEmptyExpandParam` elsewhere. For postgres, `col = ANY(${name})` binds the slice as one array param instead.
+func BindParams(txt string, driver Driver, params interface{}) (string, []interface{}, error) {
+	q, args, _, err := bindParams(txt, driver, params)
+	return q, args, err
+}
+
+// bindParams also returns the param name of each arg.
+func bindParams(txt string, driver Driver, params interface{}) (string, []interface{}, []string, error) {
+	if !strings.Contains(txt, "${") {
+		return txt, nil, nil, nil
+	}
+
+	q := utils.B(txt)
+	lst := scanParams(q)
+	if len(lst) < 1 {
+		return txt, nil, nil, nil
+	}
+
+	keys := utils.SliceMap(lst, func(_ int, p _Param) string { return p.name })
+	args, err := paramsToArgs(params, keys)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(args) != len(keys) {
+		return "", nil, nil, fmt.Errorf("0.0/internal/sqlx: expected %d params, got %d", len(keys), len(args))
+	}
+
+	var buf strings.Builder
+	var expandedArgs = make([]interface{}, 0, len(args))
+	var names = make([]string, 0, len(args))
+	cur := 0
+	for idx, param := range lst {
+		buf.Write(q[cur:param.begin])
+		cur = param.end + 1
+
+		arg := args[idx]
+		if !param.expand && !(param.inList && isExpandableValue(arg)) {
+			buf.WriteString(driver.Placeholder(len(expandedArgs), param.name))
+			expandedArgs = append(expandedArgs, arg)
+			names = append(names, param.name)
+			continue
+		}
+
+		if !isExpandableValue(arg) {
+			return "", nil, nil, fmt.Errorf("%w, `%s`", ErrBadExpandParam, param.name)
+		}
+		av := reflect.ValueOf(arg)
+		if av.Len() < 1 {
+			if !param.inList {
+				return "", nil, nil, fmt.Errorf("%w, `%s`", ErrEmptyExpandParam, param.name)
+			}
+			buf.WriteString(emptyInList)
+			continue
+		}
+		for i := 0; i < av.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(driver.Placeholder(len(expandedArgs), param.name))
+			expandedArgs = append(expandedArgs, av.Index(i).Interface())
+			names = append(names, param.name)
+		}
+	}
+	buf.Write(q[cur:])
+	// converted after the expansion, so drivers can convert the slices which are bound as one param
+	return buf.String(), convertArgs(driver, expandedArgs), names, nil
 }
 
 type Params map[string]interface{}
@@ -160,6 +310,9 @@ func paramsToArgs(params interface{}, keys []string) ([]interface{}, error) {
 	if len(keys) < 1 {
 		return nil, nil
 	}
+	if params == nil {
+		return nil, fmt.Errorf("0.0/internal/sqlx: missing key `%s`", keys[0])
+	}
 
 	t := reflect.TypeOf(params)
 	switch t {
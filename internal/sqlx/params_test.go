@@ -0,0 +1,79 @@
+package sqlx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type _TestDriver struct {
+	Driver
+}
+
+func (_ _TestDriver) Placeholder(idx int, _ string) string { return fmt.Sprintf("$%d", idx+1) }
+
+func (_ _TestDriver) Dialect() Dialect { return DefaultDialect{} }
+
+func TestBindParams(t *testing.T) {
+	cases := []struct {
+		query  string
+		params interface{}
+		out    string
+		args   []interface{}
+	}{
+		{
+			query:  "select * from a where id in (${ids}) and name = ${name}",
+			params: Params{"ids": []int64{1, 2, 3}, "name": "x"},
+			out:    "select * from a where id in ($1, $2, $3) and name = $4",
+			args:   []interface{}{int64(1), int64(2), int64(3), "x"},
+		},
+		{
+			query:  "select * from a where id not in ( ${ids} )",
+			params: Params{"ids": []int64{}},
+			out:    "select * from a where id not in ( SELECT NULL WHERE 1=0 )",
+			args:   []interface{}{},
+		},
+		{
+			query:  "select * from a where id = any(${ids}) and data = ${data}",
+			params: Params{"ids": []int64{1, 2}, "data": []byte("x")},
+			out:    "select * from a where id = any($1) and data = $2",
+			args:   []interface{}{[]int64{1, 2}, []byte("x")},
+		},
+		{
+			query:  "insert into a values (${vals...}, ${name})",
+			params: Params{"vals": [2]string{"a", "b"}, "name": "c"},
+			out:    "insert into a values ($1, $2, $3)",
+			args:   []interface{}{"a", "b", "c"},
+		},
+		{
+			query:  "select '${x}' from a where pin (${x})",
+			params: Params{"x": []int{1}},
+			out:    "select '${x}' from a where pin ($1)",
+			args:   []interface{}{[]int{1}},
+		},
+	}
+
+	for _, c := range cases {
+		q, args, err := BindParams(c.query, _TestDriver{}, c.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q != c.out || !reflect.DeepEqual(args, c.args) {
+			t.Fatalf("%s => %s %v", c.query, q, args)
+		}
+	}
+
+	if _, _, err := BindParams("select ${x...}", _TestDriver{}, Params{"x": 1}); err == nil {
+		t.Fail()
+	}
+	if _, _, err := BindParams("insert into a values (${x...}, 1)", _TestDriver{}, Params{"x": []int{}}); !errors.Is(err, ErrEmptyExpandParam) {
+		t.Fatal(err)
+	}
+	if q, args, err := BindParams("select * from a where id in (${x...})", _TestDriver{}, Params{"x": []int{}}); err != nil || q != "select * from a where id in (SELECT NULL WHERE 1=0)" || len(args) != 0 {
+		t.Fatal(q, args, err)
+	}
+	if !hasExpandParams("select ${x...}") || hasExpandParams("select ${x}") {
+		t.Fail()
+	}
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _CopyCall struct {
+	table   string
+	columns []string
+	rows    [][]any
+}
+
+type _FakeCopier struct {
+	calls []_CopyCall
+}
+
+func (c *_FakeCopier) CopyFrom(_ context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	call := _CopyCall{table: table.Sanitize(), columns: columns}
+	for src.Next() {
+		vals, err := src.Values()
+		if err != nil {
+			return 0, err
+		}
+		call.rows = append(call.rows, vals)
+	}
+	c.calls = append(c.calls, call)
+	return int64(len(call.rows)), src.Err()
+}
+
+type _CopyUser struct {
+	Id        int64  `db:"id;incr;primary"`
+	Name      string `db:"name"`
+	CreatedAt int64  `db:"created_at;default=0"`
+}
+
+func TestCopyRows(t *testing.T) {
+	groups, err := sqlx.ExtractRows("public.users", []_CopyUser{{Name: "a"}, {Name: "b", CreatedAt: 1}, {Name: "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copier := &_FakeCopier{}
+	n, err := copyRows(context.Background(), copier, groups)
+	if err != nil || n != 3 {
+		t.Fatal(n, err)
+	}
+	expected := []_CopyCall{
+		{table: `"public"."users"`, columns: []string{"name"}, rows: [][]any{{"a"}, {"c"}}},
+		{table: `"public"."users"`, columns: []string{"name", "created_at"}, rows: [][]any{{"b", int64(1)}}},
+	}
+	if !reflect.DeepEqual(copier.calls, expected) {
+		t.Fatalf("%+v", copier.calls)
+	}
+}
+
+func TestCopyFromConn(t *testing.T) {
+	db := openLockDB()
+	ctx := context.Background()
+	rows := []_CopyUser{{Name: "a"}}
+	if _, err := db.CopyFrom(ctx, nil, rows); err != ErrNotPgxConn {
+		t.Fatal(err)
+	}
+
+	// a transaction should be begun by `BeginConnTx`
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err = CopyFrom(ctx, tx, nil, rows); err != sqlx.ErrNoRawConn {
+		t.Fatal(err)
+	}
+	if n, err := CopyFrom(ctx, tx, nil, []_CopyUser{}); err != nil || n != 0 {
+		t.Fatal(n, err)
+	}
+}
@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"context"
-	"fmt"
 	"github.com/zzztttkkk/0.0/internal/sqlx"
 )
 
@@ -10,15 +9,10 @@ type DB struct {
 	*sqlx.DB
 }
 
+// EnsureExtensions likes `sqlx.DB.EnsureExtensions`, but panics on errors.
 func (db *DB) EnsureExtensions(ctx context.Context, exts ...string) *DB {
-	if len(exts) == 0 {
-		return db
-	}
-	for _, s := range exts {
-		_, e := db.Execute(ctx, fmt.Sprintf(`CREATE EXTENSION IF NOT EXISTS "%s";`, s), nil)
-		if e != nil {
-			panic(e)
-		}
+	if err := db.DB.EnsureExtensions(ctx, exts...); err != nil {
+		panic(err)
 	}
 	return db
 }
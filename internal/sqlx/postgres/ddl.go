@@ -2,11 +2,15 @@ package postgres
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/zzztttkkk/0.0/internal/sqlx"
 	"github.com/zzztttkkk/0.0/internal/utils"
+	"net"
+	"net/netip"
 	"reflect"
 	"strconv"
 	"strings"
@@ -16,6 +20,12 @@ import (
 func (_ *Driver) DDL(info *utils.FieldInfo) *sqlx.FieldDefinition {
 	var fd = &sqlx.FieldDefinition{}
 
+	if _, ok := info.Options["fts"]; ok {
+		fd.SqlType = "tsvector"
+		fd.Generated = ftsExpr(info.Options)
+		return fd
+	}
+
 	fd.SqlType = psqlType(info.Name, info.Field.Type, info.Options, fd)
 
 	_, incr := info.Options["incr"]
@@ -54,12 +64,25 @@ func getLength(v string) (int, bool) {
 }
 
 var (
-	_UuidType    = reflect.TypeOf((*pgtype.UUID)(nil)).Elem()
-	_HStoreType  = reflect.TypeOf((*pgtype.Hstore)(nil)).Elem()
-	_DateType    = reflect.TypeOf((*pgtype.Date)(nil)).Elem()
-	_NullTypes   = make(map[reflect.Type]reflect.Type)
-	_TimeType    = reflect.TypeOf((*time.Time)(nil)).Elem()
-	_AnyJsonType = reflect.TypeOf((*AnyJSON)(nil)).Elem()
+	_UuidType        = reflect.TypeOf((*pgtype.UUID)(nil)).Elem()
+	_HStoreType      = reflect.TypeOf((*pgtype.Hstore)(nil)).Elem()
+	_DateType        = reflect.TypeOf((*pgtype.Date)(nil)).Elem()
+	_TimestampType   = reflect.TypeOf((*pgtype.Timestamp)(nil)).Elem()
+	_TimestamptzType = reflect.TypeOf((*pgtype.Timestamptz)(nil)).Elem()
+	_IntervalType    = reflect.TypeOf((*pgtype.Interval)(nil)).Elem()
+	_NumericType     = reflect.TypeOf((*pgtype.Numeric)(nil)).Elem()
+	_NullTypes       = make(map[reflect.Type]reflect.Type)
+	_TimeType        = reflect.TypeOf((*time.Time)(nil)).Elem()
+	_DurationType    = reflect.TypeOf((*time.Duration)(nil)).Elem()
+	_IPType          = reflect.TypeOf((*net.IP)(nil)).Elem()
+	_IPNetType       = reflect.TypeOf((*net.IPNet)(nil)).Elem()
+	_AddrType        = reflect.TypeOf((*netip.Addr)(nil)).Elem()
+	_PrefixType      = reflect.TypeOf((*netip.Prefix)(nil)).Elem()
+	_AnyJsonType     = reflect.TypeOf((*AnyJSON)(nil)).Elem()
+	_RawJsonType     = reflect.TypeOf((*json.RawMessage)(nil)).Elem()
+	_ScannerType     = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	_ValuerType      = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	_JSONBType       = reflect.TypeOf((*interface{ jsonb() })(nil)).Elem()
 )
 
 func init() {
@@ -90,14 +113,52 @@ func psqlType(name string, t reflect.Type, opts map[string]string, fd *sqlx.Fiel
 		return userType
 	}
 
+	if enum := enumOf(t); enum != nil {
+		if fd != nil {
+			fd.Before = append(fd.Before, enum.ddl())
+		}
+		return enum.quotedName()
+	}
+
+	if nv, ok := opts["numeric"]; ok && t.Kind() != reflect.Slice {
+		if len(nv) < 1 {
+			return "numeric"
+		}
+		return fmt.Sprintf("numeric(%s)", numericArgs(nv))
+	}
+
+	if t.Implements(_JSONBType) {
+		return "jsonb"
+	}
+
 	switch t {
 	case _HStoreType:
 		return "hstore"
 	case _UuidType:
 		return "uuid"
-	case _TimeType, _DateType:
+	case _TimeType:
+		if _, ok := opts["date"]; ok {
+			return "date"
+		}
+		if _, ok := opts["tz"]; ok {
+			return "timestamptz"
+		}
 		return "timestamp"
-	case _AnyJsonType:
+	case _TimestampType:
+		return "timestamp"
+	case _TimestamptzType:
+		return "timestamptz"
+	case _DateType:
+		return "date"
+	case _DurationType, _IntervalType:
+		return "interval"
+	case _IPType, _AddrType:
+		return "inet"
+	case _IPNetType, _PrefixType:
+		return "cidr"
+	case _NumericType:
+		return "numeric"
+	case _AnyJsonType, _RawJsonType:
 		return "jsonb"
 	}
 
@@ -156,8 +217,16 @@ func psqlType(name string, t reflect.Type, opts map[string]string, fd *sqlx.Fiel
 				return "bytea"
 			}
 
-			eleSqlType := psqlType(name, t.Elem(), opts, nil)
-			return fmt.Sprintf("[]%s", eleSqlType)
+			if isJSONType(t.Elem()) {
+				return "jsonb"
+			}
+			// checks of the element type can not be applied to the array
+			efd := &sqlx.FieldDefinition{}
+			eleSqlType := psqlType(name, t.Elem(), opts, efd)
+			if fd != nil {
+				fd.Before = append(fd.Before, efd.Before...)
+			}
+			return fmt.Sprintf("%s[]", eleSqlType)
 		}
 	case reflect.Struct:
 		{
@@ -168,7 +237,53 @@ func psqlType(name string, t reflect.Type, opts map[string]string, fd *sqlx.Fiel
 				}
 				return psqlType(name, realType, opts, fd)
 			}
+			if isJSONType(t) {
+				return "jsonb"
+			}
 		}
+	case reflect.Map:
+		if isHstoreMap(t) {
+			return "hstore"
+		}
+		return "jsonb"
 	}
 	panic(fmt.Errorf("unexpect field type, %s.%s", t.PkgPath(), t.Name()))
 }
+
+// numericArgs validates `precision` or `precision,scale` of the `numeric` option.
+func numericArgs(v string) string {
+	parts := strings.Split(v, ",")
+	if len(parts) > 2 {
+		panic(fmt.Errorf("bad numeric option: `%s`", v))
+	}
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if _, e := strconv.ParseUint(p, 10, 16); e != nil {
+			panic(fmt.Errorf("bad numeric option: `%s`", v))
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, ",")
+}
+
+// isJSONType reports whether values of `t` are stored as jsonb, they are structs and maps which are not
+// handled by database/sql or pgx.
+func isJSONType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t != _HStoreType && !isHstoreMap(t)
+	case reflect.Struct:
+		if _NullTypes[t] != nil || reflect.PtrTo(t).Implements(_ScannerType) || t.Implements(_ValuerType) {
+			return false
+		}
+		switch t {
+		case _TimeType, _IPNetType, _AddrType, _PrefixType:
+			return false
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _Role string
+
+const (
+	_RoleAdmin = _Role("admin")
+	_RoleUser  = _Role("user")
+)
+
+func init() {
+	RegisterEnum("test_role", _RoleAdmin, _RoleUser)
+}
+
+type _Profile struct {
+	Bio string `json:"bio"`
+}
+
+func TestPsqlType(t *testing.T) {
+	cases := []struct {
+		v        any
+		opts     map[string]string
+		expected string
+	}{
+		{[]string{}, nil, "text[]"},
+		{[]string{}, map[string]string{"length": "~20"}, "varchar(20)[]"},
+		{[][]int32{}, nil, "integer[][]"},
+		{time.Duration(0), nil, "interval"},
+		{net.IP{}, nil, "inet"},
+		{net.IPNet{}, nil, "cidr"},
+		{float64(0), map[string]string{"numeric": "10,2"}, "numeric(10,2)"},
+		{[]float64{}, map[string]string{"numeric": "10"}, "numeric(10)[]"},
+		{pgtype.Numeric{}, nil, "numeric"},
+		{_Profile{}, nil, "jsonb"},
+		{[]_Profile{}, nil, "jsonb"},
+		{map[string]any{}, nil, "jsonb"},
+		{json.RawMessage{}, nil, "jsonb"},
+		{time.Time{}, nil, "timestamp"},
+		{time.Time{}, map[string]string{"tz": ""}, "timestamptz"},
+		{time.Time{}, map[string]string{"date": ""}, "date"},
+		{pgtype.Date{}, nil, "date"},
+		{sql.NullTime{}, map[string]string{"tz": ""}, "timestamptz"},
+		{_RoleUser, nil, "test_role"},
+		{[]_Role{}, nil, "test_role[]"},
+	}
+	for _, c := range cases {
+		fd := &sqlx.FieldDefinition{}
+		if v := psqlType("v", reflect.TypeOf(c.v), c.opts, fd); v != c.expected {
+			t.Fatalf("%T %v: expected %s, got %s", c.v, c.opts, c.expected, v)
+		}
+	}
+
+	fd := &sqlx.FieldDefinition{}
+	psqlType("v", reflect.TypeOf([]_Role{}), nil, fd)
+	if len(fd.Before) != 1 || !strings.Contains(fd.Before[0], `CREATE TYPE test_role AS ENUM ('admin', 'user')`) {
+		t.Fatal(fd.Before)
+	}
+}
+
+func TestWrapScan(t *testing.T) {
+	var tags []string
+	var roles []_Role
+	var d time.Duration
+	var ip *net.IP
+	var profile *_Profile
+	var name string
+
+	scan := func(ptr any, src any) {
+		s, ok := wrapScan(ptr).(sql.Scanner)
+		if !ok {
+			t.Fatalf("%T is not wrapped", ptr)
+		}
+		if err := s.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scan(&tags, `{a,"b c"}`)
+	scan(&roles, `{admin,user}`)
+	scan(&d, "01:00:00")
+	scan(&ip, "127.0.0.1")
+	scan(&profile, []byte(`{"bio":"x"}`))
+	if len(tags) != 2 || tags[1] != "b c" || roles[1] != _RoleUser || d != time.Hour || ip.String() != "127.0.0.1" || profile.Bio != "x" {
+		t.Fatal(tags, roles, d, ip, profile)
+	}
+	scan(&profile, nil)
+	if profile != nil {
+		t.Fatal(profile)
+	}
+	if wrapScan(&name) != &name {
+		t.Fatal("string should not be wrapped")
+	}
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type Dialect struct {
+	sqlx.DefaultDialect
+}
+
+// NormalizeDSN prefixes `postgres://` to a dsn like `user:password@host:port/db`, a url or a keyword/value dsn
+// (`host=localhost user=postgres`) is kept.
+func (_ Dialect) NormalizeDSN(dsn string) string {
+	dsn = strings.TrimSpace(dsn)
+	if len(dsn) < 1 || strings.Contains(dsn, "://") {
+		return dsn
+	}
+	if first := strings.Fields(dsn)[0]; strings.Contains(first, "=") && !strings.ContainsAny(first, "@/") {
+		return dsn
+	}
+	return "postgres://" + dsn
+}
+
+func (d Dialect) AddColumn(tablename, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", d.Quote(tablename), column)
+}
+
+func (d Dialect) CreateExtension(name string) string {
+	return fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", sqlx.QuoteIdent(name, '"'))
+}
+
+func (_ *Driver) Dialect() sqlx.Dialect { return Dialect{} }
+
+var (
+	_ sqlx.Dialect          = Dialect{}
+	_ sqlx.ExtensionCreator = Dialect{}
+)
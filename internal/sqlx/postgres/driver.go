@@ -21,5 +21,7 @@ func (_ *Driver) Open(dsn string) (driver.Connector, error) {
 func (my *Driver) Placeholder(idx int, _ string) string { return fmt.Sprintf("$%d", idx+1) }
 
 var (
-	_ sqlx.Driver = (*Driver)(nil)
+	_ sqlx.Driver       = (*Driver)(nil)
+	_ sqlx.ScanWrapper  = (*Driver)(nil)
+	_ sqlx.ArgConverter = (*Driver)(nil)
 )
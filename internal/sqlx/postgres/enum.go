@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/utils"
+)
+
+type _Enum struct {
+	name   string
+	values []string
+}
+
+var (
+	enumsLock sync.RWMutex
+	enums     = make(map[reflect.Type]*_Enum)
+)
+
+// RegisterEnum makes fields of type `T` columns of the enum type `name`, which has `values` in order.
+// The type is created before the table, values missing in an existing type are appended to it,
+// which requires postgres 12+ since it runs in a transaction. Values are never removed.
+func RegisterEnum[T ~string](name string, values ...T) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t == reflect.TypeOf("") {
+		panic(fmt.Errorf("0.0/internal/sqlx/postgres: enum `%s` should be a named string type", name))
+	}
+	if len(values) < 1 {
+		panic(fmt.Errorf("0.0/internal/sqlx/postgres: enum `%s` has no values", name))
+	}
+
+	enumsLock.Lock()
+	defer enumsLock.Unlock()
+	if _, ok := enums[t]; ok {
+		panic(fmt.Errorf("0.0/internal/sqlx/postgres: duplicate enum type `%s`", t))
+	}
+	enum := &_Enum{name: name}
+	for _, v := range values {
+		enum.values = append(enum.values, string(v))
+	}
+	enums[t] = enum
+}
+
+func enumOf(t reflect.Type) *_Enum {
+	enumsLock.RLock()
+	defer enumsLock.RUnlock()
+	return enums[t]
+}
+
+func (e *_Enum) quotedName() string { return sqlx.QuoteIdent(e.name, '"') }
+
+func quoteLiteral(v string) string { return "'" + strings.ReplaceAll(v, "'", "''") + "'" }
+
+func (e *_Enum) ddl() string {
+	var sb strings.Builder
+	name := e.quotedName()
+	sb.WriteString("DO $$ BEGIN\r\n\tCREATE TYPE ")
+	sb.WriteString(name)
+	sb.WriteString(" AS ENUM (")
+	sb.WriteString(strings.Join(utils.SliceMap(e.values, func(_ int, v string) string { return quoteLiteral(v) }), ", "))
+	sb.WriteString(");\r\nEXCEPTION WHEN duplicate_object THEN\r\n")
+	for _, v := range e.values {
+		sb.WriteString(fmt.Sprintf("\tALTER TYPE %s ADD VALUE IF NOT EXISTS %s;\r\n", name, quoteLiteral(v)))
+	}
+	sb.WriteString("END $$")
+	return sb.String()
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/utils"
+)
+
+// A `fts` field is a stored generated tsvector column of text columns, e.g. a string field tagged by
+// `db:"search;fts=nickname:A,bio:B;language=english"`. Weights are `A` to `D` and optional,
+// the language is `DefaultTextSearchLanguage` if not set. The column is indexed by gin and never written.
+
+const DefaultTextSearchLanguage = "simple"
+
+var ErrNoFTSColumn = errors.New("0.0/internal/sqlx/postgres: no fts column")
+
+type _FTSSource struct {
+	column string
+	weight string
+}
+
+func parseFTS(v string) []_FTSSource {
+	var sources []_FTSSource
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 1 {
+			continue
+		}
+		src := _FTSSource{column: item}
+		if idx := strings.IndexByte(item, ':'); idx > -1 {
+			src.column, src.weight = strings.TrimSpace(item[:idx]), strings.ToUpper(strings.TrimSpace(item[idx+1:]))
+			if len(src.weight) != 1 || src.weight[0] < 'A' || src.weight[0] > 'D' {
+				panic(fmt.Errorf("0.0/internal/sqlx/postgres: bad fts weight, `%s`", item))
+			}
+		}
+		if len(src.column) < 1 {
+			panic(fmt.Errorf("0.0/internal/sqlx/postgres: bad fts column, `%s`", item))
+		}
+		sources = append(sources, src)
+	}
+	if len(sources) < 1 {
+		panic(fmt.Errorf("0.0/internal/sqlx/postgres: empty fts columns, `%s`", v))
+	}
+	return sources
+}
+
+func ftsLanguage(opts map[string]string) string {
+	if lang := strings.TrimSpace(opts["language"]); len(lang) > 0 {
+		return lang
+	}
+	return DefaultTextSearchLanguage
+}
+
+// regconfig renders the language, generated columns require the immutable form of `to_tsvector`.
+func regconfig(language string) string {
+	if len(language) < 1 {
+		language = DefaultTextSearchLanguage
+	}
+	return quoteLiteral(language) + "::regconfig"
+}
+
+// ftsExpr renders the tsvector expression of the `fts` option.
+func ftsExpr(opts map[string]string) string {
+	lang := regconfig(ftsLanguage(opts))
+	return strings.Join(utils.SliceMap(parseFTS(opts["fts"]), func(_ int, src _FTSSource) string {
+		v := fmt.Sprintf("to_tsvector(%s, coalesce(%s, ''))", lang, Dialect{}.Quote(src.column))
+		if len(src.weight) < 1 {
+			return v
+		}
+		return fmt.Sprintf("setweight(%s, '%s')", v, src.weight)
+	}), " || ")
+}
+
+// TSQuery renders `websearch_to_tsquery(language, ${param})`, the value of `param` is in the syntax of web
+// search engines, e.g. `"sad cat" or fat -rat`.
+func TSQuery(language, param string) string {
+	return fmt.Sprintf("websearch_to_tsquery(%s, ${%s})", regconfig(language), param)
+}
+
+// TSMatch renders `column @@ TSQuery(language, param)`.
+func TSMatch(column, language, param string) string {
+	return fmt.Sprintf("%s @@ %s", Dialect{}.Quote(column), TSQuery(language, param))
+}
+
+// TSRank renders `ts_rank(column, TSQuery(language, param))`.
+func TSRank(column, language, param string) string {
+	return fmt.Sprintf("ts_rank(%s, %s)", Dialect{}.Quote(column), TSQuery(language, param))
+}
+
+// TSHeadline renders `ts_headline(language, source, TSQuery(language, param), options)`, the text of `source`
+// with matches highlighted. `options` is the options of `ts_headline`, e.g. `MaxWords=20, MinWords=5`.
+func TSHeadline(source, language, param, options string) string {
+	v := fmt.Sprintf("ts_headline(%s, coalesce(%s, ''), %s", regconfig(language), Dialect{}.Quote(source), TSQuery(language, param))
+	if len(options) > 0 {
+		v += ", " + quoteLiteral(options)
+	}
+	return v + ")"
+}
+
+type SearchOptions struct {
+	Column          string   // the `fts` column, the first one of the model if empty
+	Headlines       []string // source columns of the snippets
+	HeadlineOptions string   // see `TSHeadline`
+}
+
+type SearchResult[T any] struct {
+	Item      T
+	Rank      float64
+	Headlines map[string]string // keyed by source column
+}
+
+// ftsField returns the `fts` field named `column` of `t`, the first one if `column` is empty.
+func ftsField(t reflect.Type, column string) *utils.FieldInfo {
+	for _, info := range sqlx.DBReflectMapper.TypeMap(t).Index {
+		if _, ok := info.Options["fts"]; !ok || info.Path != info.Name {
+			continue
+		}
+		if len(column) < 1 || info.Name == column {
+			return info
+		}
+	}
+	return nil
+}
+
+// Search returns the rows of `q` which match `text`, ordered by rank, best first, the orders of `q` only break
+// ties. `q` is not changed. Empty text matches nothing.
+func Search[T any](ctx context.Context, exe sqlx.BasicExecutor, q *sqlx.Query[T], text string, opts *SearchOptions) ([]SearchResult[T], error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	info := ftsField(reflect.TypeOf((*T)(nil)).Elem(), opts.Column)
+	if info == nil {
+		return nil, ErrNoFTSColumn
+	}
+	if len(strings.TrimSpace(text)) < 1 {
+		return nil, nil
+	}
+
+	lang := ftsLanguage(info.Options)
+	rank := TSRank(info.Name, lang, "fts_query")
+	columns := []string{"*", rank}
+	for _, src := range opts.Headlines {
+		columns = append(columns, TSHeadline(src, lang, "fts_query", opts.HeadlineOptions))
+	}
+	query, params := q.Clone().
+		Where(TSMatch(info.Name, lang, "fts_query"), sqlx.Params{"fts_query": text}).
+		OrderByFirst(rank + " DESC").
+		SQL(columns...)
+
+	rows, err := exe.Rows(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult[T]
+	for rows.Next() {
+		var result SearchResult[T]
+		headlines := make([]string, len(opts.Headlines))
+		extra := []interface{}{&result.Rank}
+		for i := range headlines {
+			extra = append(extra, &headlines[i])
+		}
+		if err = rows.ScanExtra(&result.Item, extra...); err != nil {
+			return nil, err
+		}
+		if len(headlines) > 0 {
+			result.Headlines = make(map[string]string, len(headlines))
+			for i, src := range opts.Headlines {
+				result.Headlines[src] = headlines[i]
+			}
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _FTSModel struct {
+	Id     int64   `db:"id;incr;primary"`
+	Title  string  `db:"title"`
+	Body   *string `db:"body;nullable"`
+	Search string  `db:"search;fts=title:A,body:b;language=english"`
+}
+
+func (_FTSModel) TableName() string { return "fts_model" }
+
+func TestFTS(t *testing.T) {
+	ddl, indexes := sqlx.TableDDL(&Driver{}, _FTSModel{})
+	expected := `search tsvector GENERATED ALWAYS AS (` +
+		`setweight(to_tsvector('english'::regconfig, coalesce(title, '')), 'A') || ` +
+		`setweight(to_tsvector('english'::regconfig, coalesce(body, '')), 'B')) STORED NOT NULL`
+	if !strings.Contains(ddl, expected) {
+		t.Fatal(ddl)
+	}
+	if len(indexes) != 1 || !strings.Contains(indexes[0], "fts_model_search_gin ON fts_model USING gin") {
+		t.Fatal(indexes)
+	}
+
+	cases := map[string]string{
+		TSMatch("search", "", "q"):                       `search @@ websearch_to_tsquery('simple'::regconfig, ${q})`,
+		TSRank("search", "english", "q"):                 `ts_rank(search, websearch_to_tsquery('english'::regconfig, ${q}))`,
+		TSHeadline("body", "english", "q", "MaxWords=5"): `ts_headline('english'::regconfig, coalesce(body, ''), websearch_to_tsquery('english'::regconfig, ${q}), 'MaxWords=5')`,
+	}
+	for v, expected := range cases {
+		if v != expected {
+			t.Fatalf("expected %s, got %s", expected, v)
+		}
+	}
+}
+
+type _QueryRecorder struct {
+	sqlx.BasicExecutor
+	query string
+}
+
+func (r *_QueryRecorder) Rows(_ context.Context, query string, _ interface{}) (*sqlx.Rows, error) {
+	r.query = query
+	return nil, errors.New("recorded")
+}
+
+func TestSearch(t *testing.T) {
+	exe := &_QueryRecorder{}
+	q := sqlx.NewRepo[_FTSModel]().Query().Where("id > ${id}", sqlx.Params{"id": 1}).OrderBy("id DESC")
+	if _, err := Search(context.Background(), exe, q, "cat", nil); err == nil {
+		t.Fail()
+	}
+	rank := `ts_rank(search, websearch_to_tsquery('english'::regconfig, ${fts_query}))`
+	expected := `SELECT *, ` + rank + ` FROM fts_model WHERE (id > ${id}) AND ` +
+		`(search @@ websearch_to_tsquery('english'::regconfig, ${fts_query})) ORDER BY ` + rank + ` DESC, id DESC`
+	if exe.query != expected {
+		t.Fatal(exe.query)
+	}
+
+	// the query of the caller is not changed
+	if v, params := q.SQL(); v != "SELECT * FROM fts_model WHERE (id > ${id}) ORDER BY id DESC" || len(params) != 1 {
+		t.Fatal(v, params)
+	}
+}
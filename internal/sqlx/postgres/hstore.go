@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Maps of strings are hstore values, they are bound as `pgtype.Hstore`, and keys with NULL values are dropped
+// when they are scanned. Use `pgtype.Hstore` to keep NULL values.
+
+func isHstoreMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+}
+
+func toHstore(v reflect.Value) pgtype.Hstore {
+	if v.IsNil() {
+		return nil
+	}
+	h := make(pgtype.Hstore, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		s := iter.Value().String()
+		h[iter.Key().String()] = &s
+	}
+	return h
+}
+
+func (_ *Driver) ConvertArg(arg any) (any, bool) {
+	if arg == nil {
+		return nil, false
+	}
+	if m, ok := arg.(map[string]string); ok {
+		return toHstore(reflect.ValueOf(m)), true
+	}
+	if v := reflect.ValueOf(arg); isHstoreMap(v.Type()) {
+		return toHstore(v), true
+	}
+	return nil, false
+}
+
+type _HstoreScanner struct {
+	ptr reflect.Value
+}
+
+func (s *_HstoreScanner) Scan(src any) error {
+	dist := s.ptr.Elem()
+	var h pgtype.Hstore
+	if err := h.Scan(src); err != nil {
+		return err
+	}
+	if h == nil {
+		dist.Set(reflect.Zero(dist.Type()))
+		return nil
+	}
+	m := reflect.MakeMapWithSize(dist.Type(), len(h))
+	for k, v := range h {
+		if v == nil {
+			continue
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(dist.Type().Key()), reflect.ValueOf(*v).Convert(dist.Type().Elem()))
+	}
+	dist.Set(m)
+	return nil
+}
+
+// HstoreSet renders `column || ${param}`, which is used as `SET column = HstoreSet(...)` to set the keys of
+// the value of `param` atomically, other keys are kept and a NULL column is treated as empty.
+func HstoreSet(column, param string) string {
+	return fmt.Sprintf("COALESCE(%s, ''::hstore) || ${%s}::hstore", Dialect{}.Quote(column), param)
+}
+
+// HstoreDelete renders `delete(column, ${param})`, which is used as `SET column = HstoreDelete(...)` to delete
+// keys atomically, the value of `param` is a `[]string`.
+func HstoreDelete(column, param string) string {
+	return fmt.Sprintf("delete(%s, ${%s}::text[])", Dialect{}.Quote(column), param)
+}
+
+// HstoreGet renders `column -> ${param}`, the text value of the key, NULL if it does not exist.
+func HstoreGet(column, param string) string {
+	return fmt.Sprintf("%s -> ${%s}::text", Dialect{}.Quote(column), param)
+}
+
+// HstoreHasKey renders `column ? ${param}`.
+func HstoreHasKey(column, param string) string {
+	return fmt.Sprintf("%s ? ${%s}::text", Dialect{}.Quote(column), param)
+}
+
+// HstoreHasAnyKeys renders `column ?| ${param}`, the value of `param` is a `[]string`.
+func HstoreHasAnyKeys(column, param string) string {
+	return fmt.Sprintf("%s ?| ${%s}::text[]", Dialect{}.Quote(column), param)
+}
+
+// HstoreHasAllKeys renders `column ?& ${param}`, the value of `param` is a `[]string`.
+func HstoreHasAllKeys(column, param string) string {
+	return fmt.Sprintf("%s ?& ${%s}::text[]", Dialect{}.Quote(column), param)
+}
+
+// HstoreContains renders `column @> ${param}`, true if the column has all the pairs of the value of `param`.
+func HstoreContains(column, param string) string {
+	return fmt.Sprintf("%s @> ${%s}::hstore", Dialect{}.Quote(column), param)
+}
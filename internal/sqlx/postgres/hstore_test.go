@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _Labels map[string]string
+
+func TestHstore(t *testing.T) {
+	if v := psqlType("v", reflect.TypeOf(_Labels{}), nil, &sqlx.FieldDefinition{}); v != "hstore" {
+		t.Fatal(v)
+	}
+
+	q, args, err := sqlx.BindParams(
+		"UPDATE t SET labels = "+HstoreSet("labels", "labels")+" WHERE "+HstoreHasKey("labels", "key"),
+		&Driver{},
+		sqlx.Params{"labels": _Labels{"a": "1"}, "key": "a"},
+	)
+	if err != nil || q != "UPDATE t SET labels = COALESCE(labels, ''::hstore) || $1::hstore WHERE labels ? $2::text" {
+		t.Fatal(q, err)
+	}
+	h, ok := args[0].(pgtype.Hstore)
+	if !ok || *h["a"] != "1" || args[1] != "a" {
+		t.Fatal(args)
+	}
+
+	var labels _Labels
+	if err = wrapScan(&labels).(*_HstoreScanner).Scan(`"a"=>"1", "b"=>NULL`); err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels["a"] != "1" {
+		t.Fatal(labels)
+	}
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONB is a jsonb column decoded into `T`, NULL if not Valid.
+type JSONB[T any] struct {
+	Val   T
+	Valid bool
+}
+
+func NewJSONB[T any](v T) JSONB[T] { return JSONB[T]{Val: v, Valid: true} }
+
+func (v *JSONB[T]) Scan(src any) error {
+	var zero T
+	v.Val, v.Valid = zero, false
+	if src == nil {
+		return nil
+	}
+	data, err := srcBytes(src)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, &v.Val); err != nil {
+		return err
+	}
+	v.Valid = true
+	return nil
+}
+
+func (v JSONB[T]) Value() (driver.Value, error) {
+	if !v.Valid {
+		return nil, nil
+	}
+	return json.Marshal(v.Val)
+}
+
+func (v JSONB[T]) MarshalJSON() ([]byte, error) {
+	if !v.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v.Val)
+}
+
+func (v *JSONB[T]) UnmarshalJSON(data []byte) error {
+	var zero T
+	v.Val, v.Valid = zero, false
+	if string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, &v.Val); err != nil {
+		return err
+	}
+	v.Valid = true
+	return nil
+}
+
+func (v JSONB[T]) jsonb() {}
+
+var (
+	_ driver.Valuer = JSONB[any]{}
+	_ sql.Scanner   = (*JSONB[any])(nil)
+)
+
+func jsonKey(key any) string {
+	switch kv := key.(type) {
+	case string:
+		return quoteLiteral(kv)
+	case int:
+		return strconv.Itoa(kv)
+	}
+	panic(fmt.Errorf("%w, %v", ErrorUnexpectedJSONKey, key))
+}
+
+// jsonPath renders `keys` as a text array literal, e.g. `'{a,0}'`.
+func jsonPath(keys []any) string {
+	items := make([]string, len(keys))
+	for i, key := range keys {
+		switch kv := key.(type) {
+		case string:
+			items[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(kv) + `"`
+		case int:
+			items[i] = strconv.Itoa(kv)
+		default:
+			panic(fmt.Errorf("%w, %v", ErrorUnexpectedJSONKey, key))
+		}
+	}
+	return quoteLiteral("{" + strings.Join(items, ",") + "}")
+}
+
+// JSONGet renders `column->'k1'->0`, a jsonb value. Keys are strings of objects or ints of arrays, like `AnyJSON.Peek`.
+func JSONGet(column string, keys ...any) string {
+	var sb strings.Builder
+	sb.WriteString(Dialect{}.Quote(column))
+	for _, key := range keys {
+		sb.WriteString("->")
+		sb.WriteString(jsonKey(key))
+	}
+	return sb.String()
+}
+
+// JSONGetText likes `JSONGet`, but renders `->>` for the last key, a text value.
+func JSONGetText(column string, keys ...any) string {
+	if len(keys) < 1 {
+		panic(fmt.Errorf("0.0/internal/sqlx/postgres: empty json keys of `%s`", column))
+	}
+	return fmt.Sprintf("%s->>%s", JSONGet(column, keys[:len(keys)-1]...), jsonKey(keys[len(keys)-1]))
+}
+
+// JSONContains renders `column @> ${param}`. The value of `param` is marshaled as json, strings and bytes are
+// sent as json text.
+func JSONContains(column, param string) string {
+	return fmt.Sprintf("%s @> ${%s}::jsonb", Dialect{}.Quote(column), param)
+}
+
+// JSONHasKey renders `column ? ${param}`, true if the top-level keys or array strings contain the value of `param`.
+func JSONHasKey(column, param string) string {
+	return fmt.Sprintf("%s ? ${%s}", Dialect{}.Quote(column), param)
+}
+
+// JSONSet renders `jsonb_set(column, path, ${param})`, which is used as `SET column = JSONSet(...)` to update
+// the value at `keys`; a NULL column is treated as an empty object, missing parent objects are not created.
+func JSONSet(column, param string, keys ...any) string {
+	return fmt.Sprintf("jsonb_set(COALESCE(%s, '{}'), %s, ${%s}::jsonb, true)", Dialect{}.Quote(column), jsonPath(keys), param)
+}
+
+// JSONDelete renders `column #- path`, which is used as `SET column = JSONDelete(...)` to remove the value at `keys`.
+func JSONDelete(column string, keys ...any) string {
+	return fmt.Sprintf("%s #- %s", Dialect{}.Quote(column), jsonPath(keys))
+}
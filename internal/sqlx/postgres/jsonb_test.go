@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _Settings struct {
+	Theme string   `json:"theme"`
+	Tags  []string `json:"tags"`
+}
+
+type _JSONBModel struct {
+	Id       int64               `db:"id;incr;primary"`
+	Settings JSONB[_Settings]    `db:"settings;using=gin"`
+	Extra    *JSONB[[]_Settings] `db:"extra;nullable"`
+}
+
+func (_JSONBModel) TableName() string { return "jsonb_model" }
+
+func TestJSONB(t *testing.T) {
+	var v JSONB[_Settings]
+	if err := v.Scan(`{"theme":"dark","tags":["a"]}`); err != nil || !v.Valid || v.Val.Theme != "dark" || v.Val.Tags[0] != "a" {
+		t.Fatal(v, err)
+	}
+	data, err := v.Value()
+	if err != nil || string(data.([]byte)) != `{"theme":"dark","tags":["a"]}` {
+		t.Fatal(data, err)
+	}
+	if err = v.Scan(nil); err != nil || v.Valid || v.Val.Theme != "" {
+		t.Fatal(v)
+	}
+	if data, _ = v.Value(); data != nil {
+		t.Fatal(data)
+	}
+
+	ddl, indexes := sqlx.TableDDL(&Driver{}, _JSONBModel{})
+	if !strings.Contains(ddl, "settings jsonb NOT NULL") || !strings.Contains(ddl, "extra jsonb,") {
+		t.Fatal(ddl)
+	}
+	if len(indexes) != 1 || !strings.Contains(indexes[0], "jsonb_model_settings_gin ON jsonb_model USING gin") {
+		t.Fatal(indexes)
+	}
+}
+
+func TestJSONHelpers(t *testing.T) {
+	cases := map[string]string{
+		JSONGet("settings", "tags", 0):        `settings->'tags'->0`,
+		JSONGetText("settings", "theme"):      `settings->>'theme'`,
+		JSONGetText("order", "a'b", "c"):      `"order"->'a''b'->>'c'`,
+		JSONContains("settings", "v"):         `settings @> ${v}::jsonb`,
+		JSONHasKey("settings", "key"):         `settings ? ${key}`,
+		JSONSet("settings", "theme", "theme"): `jsonb_set(COALESCE(settings, '{}'), '{"theme"}', ${theme}::jsonb, true)`,
+		JSONDelete("settings", "tags", 1):     `settings #- '{"tags",1}'`,
+		JSONDelete("settings", `a"b`, "c'd"):  `settings #- '{"a\"b","c''d"}'`,
+	}
+	for v, expected := range cases {
+		if v != expected {
+			t.Fatalf("expected %s, got %s", expected, v)
+		}
+	}
+}
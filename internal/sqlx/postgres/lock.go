@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"hash/fnv"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+var (
+	ErrLockNotHeld = errors.New("0.0/internal/sqlx/postgres: advisory lock is not held")
+	ErrNotInTx     = errors.New("0.0/internal/sqlx/postgres: transaction-scoped lock outside a transaction")
+)
+
+// LockKey hashes `name` to an advisory lock key by fnv-64a.
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLock is a session-scoped advisory lock, it holds a connection until unlocked.
+type AdvisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+func (l *AdvisoryLock) Key() int64 { return l.key }
+
+// Unlock releases the lock and the connection, the connection is discarded if the lock can not be released,
+// which releases the lock too. Unlocking a nil lock, e.g. the result of a failed `TryLock`, returns `ErrLockNotHeld`.
+func (l *AdvisoryLock) Unlock(ctx context.Context) error {
+	if l == nil || l.conn == nil {
+		return ErrLockNotHeld
+	}
+	conn := l.conn
+	l.conn = nil
+	defer conn.Close()
+
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&ok); err != nil {
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Lock waits for the session-scoped advisory lock of `key`, until `ctx` is done.
+func (db *DB) Lock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := db.Raw().Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		// the lock may be acquired after the query is canceled
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = conn.Close()
+		return nil, err
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// TryLock acquires the session-scoped advisory lock of `key` without waiting, `(nil, nil)` if it is held by others,
+// so callers should check the lock, not only the error.
+func (db *DB) TryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := db.Raw().Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil || !ok {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// WithLock calls `fn` while holding the session-scoped advisory lock of `key`.
+// The lock holds a connection of the pool while `fn` runs, so `fn` needs another one to query, it blocks forever
+// if the pool has only one connection, e.g. `MaxOpenConns=1`.
+func (db *DB) WithLock(ctx context.Context, key int64, fn func(ctx context.Context) error) error {
+	lock, err := db.Lock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := lock.Unlock(context.Background()); e != nil && db.Logger() != nil {
+			db.Logger().Printf("0.0/internal/sqlx/postgres: advisory unlock error, %s", e)
+		}
+	}()
+	return fn(ctx)
+}
+
+// TxLock waits for the transaction-scoped advisory lock of `key`, it is released when the root transaction of
+// `exe` ends. `exe` should be a `*sqlx.Tx`, otherwise the lock would be released as soon as it is acquired.
+func TxLock(ctx context.Context, exe sqlx.Executor, key int64) error {
+	if _, ok := exe.(*sqlx.Tx); !ok {
+		return ErrNotInTx
+	}
+	_, err := exe.Execute(ctx, "SELECT pg_advisory_xact_lock(${key})", sqlx.Params{"key": key})
+	return err
+}
+
+// TxTryLock likes `TxLock`, but it does not wait, false if the lock is held by others.
+func TxTryLock(ctx context.Context, exe sqlx.Executor, key int64) (bool, error) {
+	if _, ok := exe.(*sqlx.Tx); !ok {
+		return false, ErrNotInTx
+	}
+	return sqlx.Scalar[bool](ctx, exe, "SELECT pg_try_advisory_xact_lock(${key})", sqlx.Params{"key": key})
+}
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+func TestLockKey(t *testing.T) {
+	if LockKey("0.0/ddl") != LockKey("0.0/ddl") || LockKey("a") == LockKey("b") {
+		t.Fail()
+	}
+}
+
+// _LockServer simulates advisory locks of a postgres server, every connection is a session.
+type _LockServer struct {
+	sync.Mutex
+	holders map[int64]*_LockConn
+}
+
+func (s *_LockServer) Connect(context.Context) (driver.Conn, error) {
+	return &_LockConn{server: s}, nil
+}
+
+func (s *_LockServer) Driver() driver.Driver { return nil }
+
+type _LockConn struct {
+	server *_LockServer
+	xact   []int64
+}
+
+func (c *_LockConn) acquire(key int64) bool {
+	if holder := c.server.holders[key]; holder != nil && holder != c {
+		return false
+	}
+	c.server.holders[key] = c
+	return true
+}
+
+func (c *_LockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.server.Lock()
+	defer c.server.Unlock()
+
+	key := args[0].Value.(int64)
+	var ok bool
+	switch {
+	case strings.Contains(query, "pg_try_advisory_lock("):
+		ok = c.acquire(key)
+	case strings.Contains(query, "pg_try_advisory_xact_lock("):
+		if ok = c.acquire(key); ok {
+			c.xact = append(c.xact, key)
+		}
+	case strings.Contains(query, "pg_advisory_unlock("):
+		if ok = c.server.holders[key] == c; ok {
+			delete(c.server.holders, key)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	return &_BoolRows{v: ok}, nil
+}
+
+// ExecContext accepts savepoints of nested transactions only.
+func (c *_LockConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	for _, prefix := range []string{"SAVEPOINT ", "RELEASE SAVEPOINT ", "ROLLBACK TO SAVEPOINT "} {
+		if strings.HasPrefix(query, prefix) {
+			return driver.RowsAffected(0), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (c *_LockConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+
+func (c *_LockConn) Close() error { return nil }
+
+func (c *_LockConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *_LockConn) Commit() error { return c.endTx() }
+
+func (c *_LockConn) Rollback() error { return c.endTx() }
+
+// endTx releases transaction-scoped locks.
+func (c *_LockConn) endTx() error {
+	c.server.Lock()
+	defer c.server.Unlock()
+	for _, key := range c.xact {
+		delete(c.server.holders, key)
+	}
+	c.xact = nil
+	return nil
+}
+
+type _BoolRows struct {
+	v    bool
+	read bool
+}
+
+func (r *_BoolRows) Columns() []string { return []string{"v"} }
+
+func (r *_BoolRows) Close() error { return nil }
+
+func (r *_BoolRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.v
+	return nil
+}
+
+type _LockDriver struct {
+	Driver
+	server *_LockServer
+}
+
+func (d *_LockDriver) Open(string) (driver.Connector, error) { return d.server, nil }
+
+func openLockDB() *DB {
+	return &DB{sqlx.MustOpenDB(&_LockDriver{server: &_LockServer{holders: map[int64]*_LockConn{}}}, "", false, nil)}
+}
+
+func TestTryLock(t *testing.T) {
+	ctx := context.Background()
+	db := openLockDB()
+	key := LockKey("0.0/test")
+
+	lock, err := db.TryLock(ctx, key)
+	if err != nil || lock == nil || lock.Key() != key {
+		t.Fatal(lock, err)
+	}
+
+	// the lock is held, no error
+	another, err := db.TryLock(ctx, key)
+	if err != nil || another != nil {
+		t.Fatal(another, err)
+	}
+	if err = another.Unlock(ctx); err != ErrLockNotHeld {
+		t.Fatal(err)
+	}
+
+	// a transaction of another session can not lock it either
+	tx := db.MustBeginTx(ctx, nil)
+	if ok, err := TxTryLock(ctx, tx, key); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+	_ = tx.Rollback()
+
+	if err = lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = lock.Unlock(ctx); err != ErrLockNotHeld {
+		t.Fatal(err)
+	}
+
+	// transaction-scoped locks are released when the transaction ends
+	tx = db.MustBeginTx(ctx, nil)
+	if ok, err := TxTryLock(ctx, tx, key); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if lock, err = db.TryLock(ctx, key); err != nil || lock != nil {
+		t.Fatal(lock, err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if lock, err = db.TryLock(ctx, key); err != nil || lock == nil {
+		t.Fatal(lock, err)
+	}
+	_ = lock.Unlock(ctx)
+
+	// the lock of a nested transaction is held by the root one
+	tx = db.MustBeginTx(ctx, nil)
+	child := tx.MustBeginTx(ctx, nil)
+	if ok, err := TxTryLock(ctx, child, key); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if lock, err = db.TryLock(ctx, key); err != nil || lock != nil {
+		t.Fatal(lock, err)
+	}
+	_ = tx.Rollback()
+
+	// not in a transaction
+	if ok, err := TxTryLock(ctx, db.DB, key); err != ErrNotInTx || ok {
+		t.Fatal(ok, err)
+	}
+	if err = TxLock(ctx, db.DB, key); err != ErrNotInTx {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type Notification = pgconn.Notification
+
+const (
+	listenMinBackoff = time.Millisecond * 100
+	listenMaxBackoff = time.Second * 10
+	// ListenBufferSize is the buffer size of the channel returned by `Listen`.
+	ListenBufferSize = 64
+)
+
+var ErrEmptyChannels = errors.New("0.0/internal/sqlx/postgres: empty listen channels")
+var ErrBadChannel = errors.New("0.0/internal/sqlx/postgres: bad listen channel")
+
+// maxIdentifierLength is `NAMEDATALEN - 1`, longer channel names are rejected by `pg_notify`.
+const maxIdentifierLength = 63
+
+// quoteChannel validates the channel name and quotes it as an identifier, names are case-sensitive.
+func quoteChannel(channel string) (string, error) {
+	if len(channel) < 1 || len(channel) > maxIdentifierLength || strings.IndexByte(channel, 0) > -1 {
+		return "", ErrBadChannel
+	}
+	return pgx.Identifier{channel}.Sanitize(), nil
+}
+
+// listenConn is the part of `*pgx.Conn` used by listeners.
+type listenConn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+}
+
+type listenFunc func(ctx context.Context, channels []string, out chan<- *Notification, ready chan<- error) (bool, error)
+
+// Notify sends a notification by `pg_notify`, inside a transaction it is delivered when the transaction commits.
+func Notify(ctx context.Context, exe sqlx.Executor, channel, payload string) error {
+	_, err := exe.Execute(ctx, "SELECT pg_notify(${channel}, ${payload})", sqlx.Params{"channel": channel, "payload": payload})
+	return err
+}
+
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	return Notify(ctx, db.DB, channel, payload)
+}
+
+// Listen listens `channels` on a connection taken from the pool, until `ctx` is done, then the returned channel
+// is closed. If the connection is broken, it reconnects and listens again; notifications sent meanwhile are lost,
+// so consumers should not rely on every one being received.
+// The connection is discarded after use, so that it does not return to the pool in the listening state.
+func (db *DB) Listen(ctx context.Context, channels ...string) (<-chan *Notification, error) {
+	if len(channels) < 1 {
+		return nil, ErrEmptyChannels
+	}
+	for _, channel := range channels {
+		if _, err := quoteChannel(channel); err != nil {
+			return nil, err
+		}
+	}
+	out := make(chan *Notification, ListenBufferSize)
+	ready := make(chan error, 1)
+	go db.listenLoop(ctx, channels, out, ready, db.listenOnce)
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// listenLoop calls `once` until `ctx` is done, with backoff between reconnections.
+func (db *DB) listenLoop(ctx context.Context, channels []string, out chan<- *Notification, ready chan<- error, once listenFunc) {
+	defer close(out)
+
+	backoff := listenMinBackoff
+	for {
+		listening, err := once(ctx, channels, out, ready)
+		if ready != nil {
+			if !listening {
+				// the first attempt failed, it is returned by `Listen`
+				ready <- err
+				return
+			}
+			ready = nil
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if listening {
+			backoff = listenMinBackoff
+		}
+		if logger := db.Logger(); logger != nil {
+			logger.Printf("0.0/internal/sqlx/postgres: listen error, reconnect after %s, %s", backoff, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > listenMaxBackoff {
+			backoff = listenMaxBackoff
+		}
+	}
+}
+
+// listenOnce returns whether `LISTEN` succeeded, and the error that broke the connection.
+func (db *DB) listenOnce(ctx context.Context, channels []string, out chan<- *Notification, ready chan<- error) (bool, error) {
+	conn, err := db.Raw().Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var listening bool
+	var cause error
+	_ = conn.Raw(func(driverConn any) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			cause = ErrNotPgxConn
+			return nil
+		}
+		listening, cause = listen(ctx, sc.Conn(), channels, out, ready)
+		return driver.ErrBadConn
+	})
+	return listening, cause
+}
+
+// listen listens `channels` on `conn` and sends notifications to `out`, until the connection is broken or `ctx`
+// is done. It returns whether `LISTEN` succeeded, and the error that stopped it.
+func listen(ctx context.Context, conn listenConn, channels []string, out chan<- *Notification, ready chan<- error) (bool, error) {
+	for _, channel := range channels {
+		quoted, err := quoteChannel(channel)
+		if err != nil {
+			return false, err
+		}
+		if _, err = conn.Exec(ctx, "LISTEN "+quoted); err != nil {
+			return false, err
+		}
+	}
+	if ready != nil {
+		ready <- nil
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		select {
+		case out <- n:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _FakeListenConn struct {
+	execs         []string
+	execErr       error
+	notifications chan *Notification
+}
+
+func newFakeListenConn() *_FakeListenConn {
+	return &_FakeListenConn{notifications: make(chan *Notification, 8)}
+}
+
+func (c *_FakeListenConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.execs = append(c.execs, sql)
+	return pgconn.CommandTag{}, c.execErr
+}
+
+// WaitForNotification returns `io.EOF` as a broken connection after `notifications` is closed.
+func (c *_FakeListenConn) WaitForNotification(ctx context.Context) (*Notification, error) {
+	select {
+	case n, ok := <-c.notifications:
+		if !ok {
+			return nil, io.EOF
+		}
+		return n, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func receive(t *testing.T, out <-chan *Notification) *Notification {
+	select {
+	case n := <-out:
+		return n
+	case <-time.After(time.Second * 3):
+		t.Fatal("no notification")
+	}
+	return nil
+}
+
+func TestQuoteChannel(t *testing.T) {
+	for channel, expected := range map[string]string{"events": `"events"`, `Order"s`: `"Order""s"`, "a.b": `"a.b"`} {
+		if v, err := quoteChannel(channel); err != nil || v != expected {
+			t.Fatal(channel, v, err)
+		}
+	}
+	for _, channel := range []string{"", strings.Repeat("a", 64), "a\x00b"} {
+		if _, err := quoteChannel(channel); err != ErrBadChannel {
+			t.Fatal(channel, err)
+		}
+	}
+
+	// channels are validated before connecting
+	db := &DB{&sqlx.DB{}}
+	if _, err := db.Listen(context.Background()); err != ErrEmptyChannels {
+		t.Fatal(err)
+	}
+	if _, err := db.Listen(context.Background(), "events", ""); err != ErrBadChannel {
+		t.Fatal(err)
+	}
+}
+
+func TestListenDispatch(t *testing.T) {
+	conn := newFakeListenConn()
+	out := make(chan *Notification, 1)
+	ready := make(chan error, 1)
+	type result struct {
+		listening bool
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		listening, err := listen(context.Background(), conn, []string{"a", "B"}, out, ready)
+		done <- result{listening, err}
+	}()
+
+	if err := <-ready; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(conn.execs, ";") != `LISTEN "a";LISTEN "B"` {
+		t.Fatal(conn.execs)
+	}
+	conn.notifications <- &Notification{Channel: "a", Payload: "1"}
+	conn.notifications <- &Notification{Channel: "B", Payload: "2"}
+	if n := receive(t, out); n.Channel != "a" || n.Payload != "1" {
+		t.Fatal(n)
+	}
+	if n := receive(t, out); n.Channel != "B" || n.Payload != "2" {
+		t.Fatal(n)
+	}
+	close(conn.notifications)
+	if r := <-done; !r.listening || r.err != io.EOF {
+		t.Fatal(r)
+	}
+
+	// a failed `LISTEN` is not ready
+	conn = newFakeListenConn()
+	conn.execErr = errors.New("denied")
+	ready = make(chan error, 1)
+	if listening, err := listen(context.Background(), conn, []string{"a"}, out, ready); listening || err != conn.execErr || len(ready) != 0 {
+		t.Fatal(listening, err)
+	}
+}
+
+func TestListenUnsubscribe(t *testing.T) {
+	// a consumer not receiving does not block the unsubscribing
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := newFakeListenConn()
+	out := make(chan *Notification)
+	conn.notifications <- &Notification{Channel: "a"}
+	time.AfterFunc(time.Millisecond*50, cancel)
+	if listening, err := listen(ctx, conn, []string{"a"}, out, nil); !listening || err != context.Canceled {
+		t.Fatal(listening, err)
+	}
+}
+
+func TestListenLoop(t *testing.T) {
+	db := &DB{&sqlx.DB{}}
+	conns := []*_FakeListenConn{newFakeListenConn(), newFakeListenConn()}
+	var calls int
+	once := func(ctx context.Context, channels []string, out chan<- *Notification, ready chan<- error) (bool, error) {
+		conn := conns[calls]
+		calls++
+		return listen(ctx, conn, channels, out, ready)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan *Notification, ListenBufferSize)
+	ready := make(chan error, 1)
+	go db.listenLoop(ctx, []string{"a"}, out, ready, once)
+	if err := <-ready; err != nil {
+		t.Fatal(err)
+	}
+
+	// the broken connection is replaced, notifications keep being delivered by the same channel
+	conns[0].notifications <- &Notification{Payload: "1"}
+	close(conns[0].notifications)
+	conns[1].notifications <- &Notification{Payload: "2"}
+	if n := receive(t, out); n.Payload != "1" {
+		t.Fatal(n)
+	}
+	if n := receive(t, out); n.Payload != "2" {
+		t.Fatal(n)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatal("unexpected notification")
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("the channel is not closed after unsubscribing")
+	}
+	if calls != 2 {
+		t.Fatal(calls)
+	}
+
+	// the error of the first attempt is returned, without reconnecting
+	conns, calls = []*_FakeListenConn{newFakeListenConn()}, 0
+	conns[0].execErr = errors.New("denied")
+	out = make(chan *Notification)
+	ready = make(chan error, 1)
+	db.listenLoop(context.Background(), []string{"a"}, out, ready, once)
+	if err := <-ready; err != conns[0].execErr || calls != 1 {
+		t.Fatal(err, calls)
+	}
+	if _, ok := <-out; ok {
+		t.Fatal("the channel is not closed")
+	}
+}
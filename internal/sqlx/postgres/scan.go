@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zzztttkkk/0.0/internal/utils"
+)
+
+// pgx/stdlib returns the text of values whose types are not known by database/sql, e.g. arrays, intervals and
+// jsonb; fields of these types are scanned by wrappers which parse the text by pgx or encoding/json.
+// Args of these types need no conversion except maps of strings(see `ConvertArg`), pgx encodes them by the types
+// of params.
+
+type _ScanKind int
+
+const (
+	_ScanDirect   = _ScanKind(iota) // handled by database/sql
+	_ScanPgx                        // parsed by pgtype.Map
+	_ScanStrings                    // slices of string kinds, parsed as text[]
+	_ScanJSON                       // jsonb
+	_ScanHstore                     // maps of strings
+	_ScanNullable                   // pointers of the types above
+)
+
+var (
+	scanKinds sync.Map // reflect.Type => _ScanKind
+	typeMaps  = sync.Pool{New: func() any { return pgtype.NewMap() }}
+)
+
+func scanKindOf(t reflect.Type) _ScanKind {
+	if v, ok := scanKinds.Load(t); ok {
+		return v.(_ScanKind)
+	}
+	kind := computeScanKind(t)
+	scanKinds.Store(t, kind)
+	return kind
+}
+
+func isPgxType(t reflect.Type) bool {
+	m := typeMaps.Get().(*pgtype.Map)
+	defer typeMaps.Put(m)
+	_, ok := m.TypeForValue(reflect.New(t).Interface())
+	return ok
+}
+
+func computeScanKind(t reflect.Type) _ScanKind {
+	if reflect.PtrTo(t).Implements(_ScannerType) {
+		return _ScanDirect
+	}
+	switch t {
+	case _TimeType:
+		return _ScanDirect
+	case _RawJsonType:
+		return _ScanJSON
+	case _DurationType, _IPType, _IPNetType, _AddrType, _PrefixType:
+		return _ScanPgx
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if scanKindOf(t.Elem()) != _ScanDirect {
+			return _ScanNullable
+		}
+	case reflect.Slice:
+		switch {
+		case t.Elem().Kind() == reflect.Uint8:
+			return _ScanDirect
+		case isJSONType(t.Elem()):
+			return _ScanJSON
+		case isPgxType(t):
+			return _ScanPgx
+		case t.Elem().Kind() == reflect.String:
+			return _ScanStrings
+		}
+	case reflect.Map:
+		if isHstoreMap(t) {
+			return _ScanHstore
+		}
+		if isJSONType(t) {
+			return _ScanJSON
+		}
+	case reflect.Struct:
+		if isJSONType(t) {
+			return _ScanJSON
+		}
+	}
+	return _ScanDirect
+}
+
+func (_ *Driver) WrapScan(ptr any) any { return wrapScan(ptr) }
+
+func wrapScan(ptr any) any {
+	pv := reflect.ValueOf(ptr)
+	if pv.Kind() != reflect.Ptr {
+		return ptr
+	}
+	switch scanKindOf(pv.Type().Elem()) {
+	case _ScanPgx:
+		return &_PgxScanner{ptr: ptr}
+	case _ScanStrings:
+		return &_StringsScanner{ptr: pv}
+	case _ScanJSON:
+		return &_JSONScanner{ptr: pv}
+	case _ScanHstore:
+		return &_HstoreScanner{ptr: pv}
+	case _ScanNullable:
+		return &_NullableScanner{ptr: pv}
+	}
+	return ptr
+}
+
+func srcBytes(src any) ([]byte, error) {
+	switch v := src.(type) {
+	case string:
+		return utils.B(v), nil
+	case []byte:
+		return v, nil
+	}
+	return nil, fmt.Errorf("0.0/internal/sqlx/postgres: unexpected scan source type %T", src)
+}
+
+type _PgxScanner struct {
+	ptr any
+}
+
+func (s *_PgxScanner) Scan(src any) error {
+	m := typeMaps.Get().(*pgtype.Map)
+	defer typeMaps.Put(m)
+	dt, ok := m.TypeForValue(s.ptr)
+	if !ok {
+		return fmt.Errorf("0.0/internal/sqlx/postgres: unknown scan type %T", s.ptr)
+	}
+	if src == nil {
+		return m.Scan(dt.OID, pgtype.TextFormatCode, nil, s.ptr)
+	}
+	data, err := srcBytes(src)
+	if err != nil {
+		return err
+	}
+	return m.Scan(dt.OID, pgtype.TextFormatCode, data, s.ptr)
+}
+
+// _StringsScanner scans arrays into slices of named string types, e.g. enums.
+type _StringsScanner struct {
+	ptr reflect.Value
+}
+
+func (s *_StringsScanner) Scan(src any) error {
+	dist := s.ptr.Elem()
+	if src == nil {
+		dist.Set(reflect.Zero(dist.Type()))
+		return nil
+	}
+	data, err := srcBytes(src)
+	if err != nil {
+		return err
+	}
+	m := typeMaps.Get().(*pgtype.Map)
+	defer typeMaps.Put(m)
+	var items []string
+	if err = m.Scan(pgtype.TextArrayOID, pgtype.TextFormatCode, data, &items); err != nil {
+		return err
+	}
+	sv := reflect.MakeSlice(dist.Type(), len(items), len(items))
+	for i, item := range items {
+		sv.Index(i).SetString(item)
+	}
+	dist.Set(sv)
+	return nil
+}
+
+type _JSONScanner struct {
+	ptr reflect.Value
+}
+
+func (s *_JSONScanner) Scan(src any) error {
+	if src == nil {
+		dist := s.ptr.Elem()
+		dist.Set(reflect.Zero(dist.Type()))
+		return nil
+	}
+	data, err := srcBytes(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, s.ptr.Interface())
+}
+
+// _NullableScanner scans NULL as nil, and other values by the wrapper of the pointed type.
+type _NullableScanner struct {
+	ptr reflect.Value
+}
+
+func (s *_NullableScanner) Scan(src any) error {
+	dist := s.ptr.Elem()
+	if src == nil {
+		dist.Set(reflect.Zero(dist.Type()))
+		return nil
+	}
+	v := reflect.New(dist.Type().Elem())
+	if err := wrapScan(v.Interface()).(sql.Scanner).Scan(src); err != nil {
+		return err
+	}
+	dist.Set(v)
+	return nil
+}
@@ -0,0 +1,190 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type QueryKind int
+
+const (
+	QueryExec QueryKind = iota + 1
+	QueryRows
+	QueryPrepare
+	QueryTxBegin
+	QueryTxCommit
+	QueryTxRollback
+)
+
+func (k QueryKind) String() string {
+	switch k {
+	case QueryExec:
+		return "exec"
+	case QueryRows:
+		return "rows"
+	case QueryPrepare:
+		return "prepare"
+	case QueryTxBegin:
+		return "begin"
+	case QueryTxCommit:
+		return "commit"
+	case QueryTxRollback:
+		return "rollback"
+	}
+	return "unknown"
+}
+
+type QueryEvent struct {
+	Kind  QueryKind
+	Query string
+	Args  []interface{}
+	// Names are the param names of Args, empty if the args are not bound from `${name}`.
+	Names []string
+	Stmt  bool // executed by a prepared statement
+	Tx    *Tx  // nil if not in a transaction
+
+	Start        time.Time
+	Duration     time.Duration
+	RowsAffected int64 // -1 if unknown
+	Err          error
+}
+
+// QueryHook is called before and after every query, statement and top-level transaction event of a `DB`.
+// Savepoints of nested transactions are reported as `QueryExec` events.
+type QueryHook interface {
+	// BeforeQuery can return a derived context, which is passed to AfterQuery, e.g. to carry a tracing span.
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// AddQueryHook should be called before the db is used, it is not concurrency safe.
+// The `LogQueryHook` installed for the logger of the db is removed once a `LogQueryHook` or a `RedactArgs` hook is added,
+// so the args are not logged twice, or unredacted.
+func (db *DB) AddQueryHook(hooks ...QueryHook) *DB {
+	for _, hook := range hooks {
+		if db.autoLog && isLogHook(hook) {
+			db.hooks = db.hooks[1:]
+			db.autoLog = false
+		}
+	}
+	db.hooks = append(db.hooks, hooks...)
+	return db
+}
+
+// logQueries keeps the query logging of dbs opened with a logger.
+func (db *DB) logQueries() {
+	if db.logger == nil {
+		return
+	}
+	db.hooks = append([]QueryHook{LogQueryHook{Logger: db.logger}}, db.hooks...)
+	db.autoLog = true
+}
+
+func isLogHook(hook QueryHook) bool {
+	switch hook.(type) {
+	case LogQueryHook, *LogQueryHook, *_RedactHook:
+		return true
+	}
+	return false
+}
+
+func (db *DB) beforeQuery(ctx context.Context, event *QueryEvent) (context.Context, *QueryEvent) {
+	if len(db.hooks) < 1 {
+		return ctx, nil
+	}
+	event.RowsAffected = -1
+	for _, hook := range db.hooks {
+		ctx = hook.BeforeQuery(ctx, event)
+	}
+	event.Start = time.Now()
+	return ctx, event
+}
+
+func (db *DB) afterQuery(ctx context.Context, event *QueryEvent, result sql.Result, err error) {
+	if event == nil {
+		return
+	}
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+	if err == nil && result != nil {
+		if n, e := result.RowsAffected(); e == nil {
+			event.RowsAffected = n
+		}
+	}
+	for i := len(db.hooks) - 1; i >= 0; i-- {
+		db.hooks[i].AfterQuery(ctx, event)
+	}
+}
+
+// LogQueryHook logs every event after it is done.
+type LogQueryHook struct {
+	Logger Logger
+}
+
+func (h LogQueryHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context { return ctx }
+
+func (h LogQueryHook) AfterQuery(_ context.Context, e *QueryEvent) {
+	h.Logger.Printf(
+		"0.0/internal/sqlx: kind=%s duration=%s rows=%d query=%q args=%v stmt=%t tx=%p err=%v",
+		e.Kind, e.Duration, e.RowsAffected, e.Query, e.Args, e.Stmt, e.Tx, e.Err,
+	)
+}
+
+// SlowQueryHook logs events that take longer than Threshold.
+type SlowQueryHook struct {
+	Threshold time.Duration
+	Logger    Logger
+}
+
+func (h SlowQueryHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context { return ctx }
+
+func (h SlowQueryHook) AfterQuery(_ context.Context, e *QueryEvent) {
+	if e.Duration < h.Threshold {
+		return
+	}
+	h.Logger.Printf(
+		"0.0/internal/sqlx: slow query, kind=%s duration=%s threshold=%s query=%q args=%v err=%v",
+		e.Kind, e.Duration, h.Threshold, e.Query, e.Args, e.Err,
+	)
+}
+
+const RedactedArg = "[REDACTED]"
+
+type _RedactHook struct {
+	hook  QueryHook
+	names map[string]bool
+}
+
+// RedactArgs wraps `hook`, the args of params named in `names`(case-insensitive) are replaced with `RedactedArg`
+// in the events passed to it. Params are usually named after columns, e.g. `${password}`.
+func RedactArgs(hook QueryHook, names ...string) QueryHook {
+	h := &_RedactHook{hook: hook, names: make(map[string]bool, len(names))}
+	for _, name := range names {
+		h.names[strings.ToLower(name)] = true
+	}
+	return h
+}
+
+func (h *_RedactHook) redact(e *QueryEvent) *QueryEvent {
+	v := *e
+	for i, name := range e.Names {
+		if i >= len(e.Args) || !h.names[strings.ToLower(name)] {
+			continue
+		}
+		if &v.Args[0] == &e.Args[0] {
+			v.Args = append([]interface{}(nil), e.Args...)
+		}
+		v.Args[i] = RedactedArg
+	}
+	return &v
+}
+
+func (h *_RedactHook) BeforeQuery(ctx context.Context, e *QueryEvent) context.Context {
+	return h.hook.BeforeQuery(ctx, h.redact(e))
+}
+
+func (h *_RedactHook) AfterQuery(ctx context.Context, e *QueryEvent) {
+	h.hook.AfterQuery(ctx, h.redact(e))
+}
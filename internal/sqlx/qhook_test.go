@@ -0,0 +1,149 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type _RecordHook struct {
+	events []QueryEvent
+}
+
+func (h *_RecordHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context { return ctx }
+
+func (h *_RecordHook) AfterQuery(_ context.Context, e *QueryEvent) { h.events = append(h.events, *e) }
+
+type _TestResult int64
+
+func (r _TestResult) LastInsertId() (int64, error) { return 0, errors.New("unsupported") }
+
+func (r _TestResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestQueryHook(t *testing.T) {
+	q, args, names, err := bindParams(
+		"UPDATE user SET password=${password} WHERE id IN (${ids})",
+		_TestDriver{},
+		Params{"password": "123456", "ids": []int{1, 2}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"password", "ids", "ids"}) {
+		t.Fatal(names)
+	}
+
+	record := &_RecordHook{}
+	raw := &_RecordHook{}
+	db := (&DB{}).AddQueryHook(raw, RedactArgs(record, "Password"))
+	ctx, event := db.beforeQuery(context.Background(), &QueryEvent{Kind: QueryExec, Query: q, Args: args, Names: names})
+	db.afterQuery(ctx, event, _TestResult(2), nil)
+
+	if len(record.events) != 1 || record.events[0].RowsAffected != 2 {
+		t.Fatal(record.events)
+	}
+	if !reflect.DeepEqual(record.events[0].Args, []interface{}{RedactedArg, 1, 2}) {
+		t.Fatal(record.events[0].Args)
+	}
+	if raw.events[0].Args[0] != "123456" {
+		t.Fatal(raw.events[0].Args)
+	}
+
+	if _, event = (&DB{}).beforeQuery(context.Background(), &QueryEvent{}); event != nil {
+		t.Fail()
+	}
+}
+
+type _BufLogger struct {
+	strings.Builder
+}
+
+func (l *_BufLogger) Printf(format string, args ...interface{}) {
+	l.WriteString(fmt.Sprintf(format, args...))
+	l.WriteRune('\n')
+}
+
+func TestLogRedactedArgs(t *testing.T) {
+	db, _ := newFakeDB()
+	logger := &_BufLogger{}
+	db.logger = logger
+	db.AddQueryHook(RedactArgs(LogQueryHook{Logger: logger}, "password"))
+	ctx := context.Background()
+
+	if _, err := db.Execute(ctx, "UPDATE user SET password=${password}", Params{"password": "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	tx := db.MustBeginTx(ctx, nil)
+	if _, err := tx.Execute(ctx, "UPDATE user SET password=${password}", Params{"password": "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tx.Prepare(ctx, "UPDATE user SET password=${password}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stmt.Execute(ctx, Params{"password": "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = tx.Commit()
+
+	if strings.Contains(logger.String(), "123456") || strings.Count(logger.String(), RedactedArg) != 3 {
+		t.Fatal(logger.String())
+	}
+}
+
+type _OpenTestDriver struct {
+	_TestDriver
+	conn *_FakeConn
+}
+
+func (d _OpenTestDriver) Open(string) (driver.Connector, error) {
+	return _FakeConnector{conn: d.conn}, nil
+}
+
+func (d _OpenTestDriver) Dialect() Dialect { return DefaultDialect{} }
+
+func TestOpenDBLogQueries(t *testing.T) {
+	ctx := context.Background()
+	newDB := func(logger Logger) *DB {
+		conn := &_FakeConn{prepared: map[string]int{}, closed: map[string]int{}, results: map[string]*_FakeRows{}}
+		return MustOpenDB(_OpenTestDriver{conn: conn}, "", false, logger)
+	}
+
+	logger := &_BufLogger{}
+	db := newDB(logger)
+	if _, err := db.Execute(ctx, "UPDATE user SET password=${password}", Params{"password": "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logger.String(), "UPDATE user SET password=$1") || !strings.Contains(logger.String(), "123456") {
+		t.Fatal(logger.String())
+	}
+
+	// other hooks keep the logging
+	logger = &_BufLogger{}
+	db = newDB(logger).AddQueryHook(SlowQueryHook{Threshold: time.Hour, Logger: logger})
+	if _, err := db.Execute(ctx, "UPDATE user SET name=${name}", Params{"name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(logger.String(), "UPDATE user") != 1 {
+		t.Fatal(logger.String())
+	}
+
+	// a redacting hook replaces it
+	logger = &_BufLogger{}
+	db = newDB(logger).AddQueryHook(RedactArgs(LogQueryHook{Logger: logger}, "password"))
+	if _, err := db.Execute(ctx, "UPDATE user SET password=${password}", Params{"password": "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logger.String(), "123456") || strings.Count(logger.String(), RedactedArg) != 1 {
+		t.Fatal(logger.String())
+	}
+
+	if db = newDB(nil); len(db.hooks) != 0 {
+		t.Fatal(db.hooks)
+	}
+}
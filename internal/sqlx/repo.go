@@ -0,0 +1,552 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zzztttkkk/0.0/internal/utils"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Repo is the struct-based access of the table of model `T`.
+//
+// If `T` has a field tagged with `softdelete`, `Delete` marks rows deleted instead of deleting them,
+// and queries skip deleted rows by default.
+type Repo[T any] struct {
+	table      string
+	typ        reflect.Type
+	primary    []*utils.FieldInfo
+	softDelete *utils.FieldInfo
+	version    *utils.FieldInfo
+}
+
+var (
+	ErrEmptyPrimaryKey = errors.New("0.0/internal/sqlx: empty primary key")
+	// ErrStaleObject is returned by `Repo.Update` if the row was changed or deleted after it has been read.
+	ErrStaleObject = errors.New("0.0/internal/sqlx: stale object")
+)
+
+func NewRepo[T any]() *Repo[T] {
+	t, err := structTypeOf[T]()
+	if err != nil {
+		panic(err)
+	}
+	repo := &Repo[T]{
+		typ:        t,
+		table:      tableNameOf(reflect.New(t).Elem()),
+		softDelete: softDeleteField(t),
+		version:    versionField(t),
+	}
+	for _, info := range modelFields(t) {
+		if _, ok := info.Options["primary"]; ok {
+			repo.primary = append(repo.primary, info)
+		}
+	}
+	return repo
+}
+
+func (repo *Repo[T]) Table() string { return repo.table }
+
+func (repo *Repo[T]) quotedTable(exe interface{}) string { return dialectOf(exe).Quote(repo.table) }
+
+// alive returns the condition of rows not soft deleted, quoted by the dialect of `exe`.
+func (repo *Repo[T]) alive(exe interface{}) string {
+	return aliveCondition(dialectOf(exe).Quote, repo.softDelete)
+}
+
+// SoftDelete reports whether `T` supports soft delete.
+func (repo *Repo[T]) SoftDelete() bool { return repo.softDelete != nil }
+
+// wherePrimary appends `WHERE pk0 = ${pk0} AND ...` and the primary key values of `mv`, columns are quoted by `quote`.
+func (repo *Repo[T]) wherePrimary(quote func(string) string, sb *strings.Builder, args []interface{}, mv reflect.Value) ([]interface{}, error) {
+	if len(repo.primary) < 1 {
+		return nil, ErrEmptyPrimaryKey
+	}
+	sb.WriteString(" WHERE ")
+	for i, info := range repo.primary {
+		fv := utils.FieldByIndexesReadOnly(mv, info.Index)
+		if fv.IsZero() {
+			return nil, ErrEmptyPrimaryKey
+		}
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString(fmt.Sprintf("%s = ${%s}", quote(info.Name), info.Name))
+		args = append(args, fv.Interface())
+	}
+	return args, nil
+}
+
+func (repo *Repo[T]) execute(ctx context.Context, exe Executor, query string, args []interface{}) (int64, error) {
+	result, err := exe.Execute(ctx, query, args)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Insert inserts `model`, zero generated columns(`incr` or `default`) are filled back.
+func (repo *Repo[T]) Insert(ctx context.Context, exe Executor, model *T) error {
+	if err := InvokeHook(ctx, exe, HookBeforeInsert, model); err != nil {
+		return err
+	}
+	if err := repo.insert(ctx, exe, model); err != nil {
+		return err
+	}
+	return InvokeHook(ctx, exe, HookAfterInsert, model)
+}
+
+func (repo *Repo[T]) insert(ctx context.Context, exe Executor, model *T) error {
+	mv := reflect.ValueOf(model).Elem()
+	fields := insertFields(repo.typ, []reflect.Value{mv})
+	if len(fields) < 1 {
+		return fmt.Errorf("0.0/internal/sqlx: `%s` got empty columns", repo.typ)
+	}
+
+	quote := dialectOf(exe).Quote
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(fields))
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(repo.quotedTable(exe))
+	sb.WriteString(" (")
+	sb.WriteString(quoteJoin(quote, utils.SliceMap(fields, func(_ int, f *utils.FieldInfo) string { return f.Name })))
+	sb.WriteString(") VALUES (")
+	for i, info := range fields {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("${%s}", info.Name))
+		args = append(args, utils.FieldByIndexesReadOnly(mv, info.Index).Interface())
+	}
+	sb.WriteRune(')')
+
+	var returning []string
+	for _, info := range modelFields(repo.typ) {
+		if isGeneratedField(info) && utils.SliceFind(fields, info) < 0 {
+			returning = append(returning, info.Name)
+		}
+	}
+	if len(returning) < 1 {
+		_, err := exe.Execute(ctx, sb.String(), args)
+		return err
+	}
+	if !dialectOf(exe).Returning() {
+		return repo.insertWithoutReturning(ctx, exe, mv, sb.String(), args, returning)
+	}
+
+	sb.WriteString(" RETURNING ")
+	sb.WriteString(quoteJoin(quote, returning))
+	rows, err := exe.Rows(ctx, sb.String(), args)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return rows.fetchOne(model)
+}
+
+// insertWithoutReturning fills the `incr` column by the last insert id, then selects other generated columns
+// by the primary key.
+func (repo *Repo[T]) insertWithoutReturning(
+	ctx context.Context, exe Executor, mv reflect.Value, query string, args []interface{}, returning []string,
+) error {
+	result, err := exe.Execute(ctx, query, args)
+	if err != nil {
+		return err
+	}
+
+	smap := DBReflectMapper.TypeMap(repo.typ)
+	var rest []string
+	for _, name := range returning {
+		info := smap.Names[name]
+		if _, ok := info.Options["incr"]; !ok {
+			rest = append(rest, name)
+			continue
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		fv := utils.FieldByIndexes(mv, info.Index)
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fv.SetInt(id)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fv.SetUint(uint64(id))
+		default:
+			return fmt.Errorf("0.0/internal/sqlx: `incr` column `%s` is not an integer", name)
+		}
+	}
+	if len(rest) < 1 {
+		return nil
+	}
+
+	quote := dialectOf(exe).Quote
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(quoteJoin(quote, rest))
+	sb.WriteString(" FROM ")
+	sb.WriteString(repo.quotedTable(exe))
+	if args, err = repo.wherePrimary(quote, &sb, nil, mv); err != nil {
+		return err
+	}
+	rows, err := exe.Rows(ctx, sb.String(), args)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return rows.fetchOne(mv.Addr().Interface())
+}
+
+// Update updates `columns` of `model` by the primary key, all non-primary columns if `columns` is empty.
+// Soft deleted rows are not updated.
+//
+// If `T` has a field tagged with `version`, the row is updated only if its version is not changed, and the
+// version is increased, otherwise `ErrStaleObject` is returned.
+func (repo *Repo[T]) Update(ctx context.Context, exe Executor, model *T, columns ...string) (int64, error) {
+	if err := InvokeHook(ctx, exe, HookBeforeUpdate, model); err != nil {
+		return 0, err
+	}
+	n, err := repo.update(ctx, exe, model, columns)
+	if err != nil || n < 1 {
+		return n, err
+	}
+	return n, InvokeHook(ctx, exe, HookAfterUpdate, model)
+}
+
+func (repo *Repo[T]) update(ctx context.Context, exe Executor, model *T, columns []string) (int64, error) {
+	mv := reflect.ValueOf(model).Elem()
+	smap := DBReflectMapper.TypeMap(repo.typ)
+	if len(columns) < 1 {
+		for _, info := range modelFields(repo.typ) {
+			if _, ok := info.Options["primary"]; !ok && info != repo.softDelete && !isComputedField(info) {
+				columns = append(columns, info.Name)
+			}
+		}
+	}
+
+	quote := dialectOf(exe).Quote
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(columns)+len(repo.primary)+2)
+	sb.WriteString("UPDATE ")
+	sb.WriteString(repo.quotedTable(exe))
+	sb.WriteString(" SET ")
+	for _, c := range columns {
+		info := smap.Names[c]
+		if info == nil {
+			return 0, fmt.Errorf("0.0/internal/sqlx: `%s` is not a column of `%s`", c, repo.table)
+		}
+		if isComputedField(info) {
+			return 0, fmt.Errorf("0.0/internal/sqlx: `%s` is a computed column of `%s`", c, repo.table)
+		}
+		if info == repo.version {
+			continue
+		}
+		if len(args) > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("%s = ${%s}", quote(c), c))
+		args = append(args, utils.FieldByIndexesReadOnly(mv, info.Index).Interface())
+	}
+
+	var oldVersion, newVersion reflect.Value
+	if repo.version != nil {
+		oldVersion = utils.FieldByIndexesReadOnly(mv, repo.version.Index)
+		var err error
+		if newVersion, err = nextVersion(oldVersion); err != nil {
+			return 0, err
+		}
+		if len(args) > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("%s = ${%s}", quote(repo.version.Name), repo.version.Name))
+		args = append(args, newVersion.Interface())
+	}
+	if len(args) < 1 {
+		return 0, fmt.Errorf("0.0/internal/sqlx: empty update columns of `%s`", repo.table)
+	}
+
+	args, err := repo.wherePrimary(quote, &sb, args, mv)
+	if err != nil {
+		return 0, err
+	}
+	if repo.softDelete != nil {
+		sb.WriteString(" AND ")
+		sb.WriteString(repo.alive(exe))
+	}
+	if repo.version == nil {
+		return repo.execute(ctx, exe, sb.String(), args)
+	}
+
+	sb.WriteString(fmt.Sprintf(" AND %s = ${%s}", quote(repo.version.Name), repo.version.Name))
+	args = append(args, oldVersion.Interface())
+	n, err := repo.execute(ctx, exe, sb.String(), args)
+	if err != nil {
+		return n, err
+	}
+	if n < 1 {
+		return 0, ErrStaleObject
+	}
+	oldVersion.Set(newVersion)
+	return n, nil
+}
+
+func (repo *Repo[T]) setSoftDelete(ctx context.Context, exe Executor, model *T, value reflect.Value, cond string) (int64, error) {
+	mv := reflect.ValueOf(model).Elem()
+	quote := dialectOf(exe).Quote
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("UPDATE %s SET %s = ${%s}", repo.quotedTable(exe), quote(repo.softDelete.Name), repo.softDelete.Name))
+	args, err := repo.wherePrimary(quote, &sb, []interface{}{value.Interface()}, mv)
+	if err != nil {
+		return 0, err
+	}
+	sb.WriteString(" AND ")
+	sb.WriteString(cond)
+
+	n, err := repo.execute(ctx, exe, sb.String(), args)
+	if err == nil && n > 0 {
+		utils.FieldByIndexes(mv, repo.softDelete.Index).Set(value)
+	}
+	return n, err
+}
+
+// Delete marks `model` deleted if `T` supports soft delete, otherwise deletes it from the table.
+func (repo *Repo[T]) Delete(ctx context.Context, exe Executor, model *T) (int64, error) {
+	if err := InvokeHook(ctx, exe, HookBeforeDelete, model); err != nil {
+		return 0, err
+	}
+	var n int64
+	var err error
+	if repo.softDelete == nil {
+		n, err = repo.purge(ctx, exe, model)
+	} else {
+		var value reflect.Value
+		if value, err = softDeleteValue(repo.softDelete.Field.Type, time.Now()); err != nil {
+			return 0, err
+		}
+		n, err = repo.setSoftDelete(ctx, exe, model, value, repo.alive(exe))
+	}
+	if err != nil || n < 1 {
+		return n, err
+	}
+	return n, InvokeHook(ctx, exe, HookAfterDelete, model)
+}
+
+// Restore un-deletes a soft deleted `model`.
+func (repo *Repo[T]) Restore(ctx context.Context, exe Executor, model *T) (int64, error) {
+	if repo.softDelete == nil {
+		return 0, fmt.Errorf("0.0/internal/sqlx: `%s` does not support soft delete", repo.typ)
+	}
+	cond := fmt.Sprintf("NOT (%s)", repo.alive(exe))
+	return repo.setSoftDelete(ctx, exe, model, reflect.Zero(repo.softDelete.Field.Type), cond)
+}
+
+// Purge deletes `model` from the table, even if `T` supports soft delete.
+func (repo *Repo[T]) Purge(ctx context.Context, exe Executor, model *T) (int64, error) {
+	if err := InvokeHook(ctx, exe, HookBeforeDelete, model); err != nil {
+		return 0, err
+	}
+	n, err := repo.purge(ctx, exe, model)
+	if err != nil || n < 1 {
+		return n, err
+	}
+	return n, InvokeHook(ctx, exe, HookAfterDelete, model)
+}
+
+func (repo *Repo[T]) purge(ctx context.Context, exe Executor, model *T) (int64, error) {
+	var sb strings.Builder
+	sb.WriteString("DELETE FROM ")
+	sb.WriteString(repo.quotedTable(exe))
+	args, err := repo.wherePrimary(dialectOf(exe).Quote, &sb, nil, reflect.ValueOf(model).Elem())
+	if err != nil {
+		return 0, err
+	}
+	return repo.execute(ctx, exe, sb.String(), args)
+}
+
+// PurgeDeleted deletes rows soft deleted before `before`.
+func (repo *Repo[T]) PurgeDeleted(ctx context.Context, exe Executor, before time.Time) (int64, error) {
+	if repo.softDelete == nil {
+		return 0, fmt.Errorf("0.0/internal/sqlx: `%s` does not support soft delete", repo.typ)
+	}
+	value, err := softDeleteValue(repo.softDelete.Field.Type, before)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE NOT (%s) AND %s < ${before}",
+		repo.quotedTable(exe), repo.alive(exe), dialectOf(exe).Quote(repo.softDelete.Name),
+	)
+	return repo.execute(ctx, exe, query, []interface{}{value.Interface()})
+}
+
+// Query returns a new query of `T`.
+func (repo *Repo[T]) Query() *Query[T] { return &Query[T]{repo: repo} }
+
+type _DeletedMode int
+
+const (
+	_ExcludeDeleted = _DeletedMode(iota)
+	_IncludeDeleted
+	_OnlyDeleted
+)
+
+// Query is a simple select builder of `Repo`, conditions are joined by `AND`.
+type Query[T any] struct {
+	repo    *Repo[T]
+	conds   []string
+	params  Params
+	orderBy []string
+	limit   int
+	offset  int
+	deleted _DeletedMode
+	err     error
+}
+
+// Where appends a condition, `params` is a `Params`, a `map[string]interface{}`, a struct or nil.
+func (q *Query[T]) Where(cond string, params interface{}) *Query[T] {
+	q.conds = append(q.conds, cond)
+	m, err := paramsToMap(params)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	if len(m) > 0 && q.params == nil {
+		q.params = make(Params, len(m))
+	}
+	for k, v := range m {
+		q.params[k] = v
+	}
+	return q
+}
+
+// WithDeleted makes the query include soft deleted rows.
+func (q *Query[T]) WithDeleted() *Query[T] {
+	q.deleted = _IncludeDeleted
+	return q
+}
+
+// OnlyDeleted makes the query return soft deleted rows only.
+func (q *Query[T]) OnlyDeleted() *Query[T] {
+	q.deleted = _OnlyDeleted
+	return q
+}
+
+func (q *Query[T]) OrderBy(exprs ...string) *Query[T] {
+	q.orderBy = append(q.orderBy, exprs...)
+	return q
+}
+
+// OrderByFirst likes `OrderBy`, but `exprs` are placed before the existing orders.
+func (q *Query[T]) OrderByFirst(exprs ...string) *Query[T] {
+	q.orderBy = append(append([]string{}, exprs...), q.orderBy...)
+	return q
+}
+
+// Clone returns a copy of `q`, changing the copy does not change `q`.
+func (q *Query[T]) Clone() *Query[T] {
+	c := *q
+	c.conds = append([]string(nil), q.conds...)
+	c.orderBy = append([]string(nil), q.orderBy...)
+	if q.params != nil {
+		c.params = make(Params, len(q.params))
+		for k, v := range q.params {
+			c.params[k] = v
+		}
+	}
+	return &c
+}
+
+func (q *Query[T]) Limit(limit int) *Query[T] {
+	q.limit = limit
+	return q
+}
+
+func (q *Query[T]) Offset(offset int) *Query[T] {
+	q.offset = offset
+	return q
+}
+
+func (q *Query[T]) where(dialect Dialect) string {
+	conds := utils.SliceMap(q.conds, func(_ int, c string) string { return "(" + c + ")" })
+	if sf := q.repo.softDelete; sf != nil {
+		switch q.deleted {
+		case _ExcludeDeleted:
+			conds = append(conds, aliveCondition(dialect.Quote, sf))
+		case _OnlyDeleted:
+			conds = append(conds, fmt.Sprintf("NOT (%s)", aliveCondition(dialect.Quote, sf)))
+		}
+	}
+	if len(conds) < 1 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// SQL returns the query and its params, `columns` is `*` if empty.
+// The table name is quoted by `DefaultDialect` if needed, the query methods use the dialect of the executor.
+func (q *Query[T]) SQL(columns ...string) (string, Params) { return q.sql(DefaultDialect{}, columns) }
+
+func (q *Query[T]) sql(dialect Dialect, columns []string) (string, Params) {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(columns) < 1 {
+		sb.WriteRune('*')
+	} else {
+		sb.WriteString(strings.Join(columns, ", "))
+	}
+	sb.WriteString(" FROM ")
+	sb.WriteString(dialect.Quote(q.repo.table))
+	sb.WriteString(q.where(dialect))
+	if len(q.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	if q.limit > 0 {
+		sb.WriteString(fmt.Sprintf(" LIMIT %d", q.limit))
+	}
+	if q.offset > 0 {
+		sb.WriteString(fmt.Sprintf(" OFFSET %d", q.offset))
+	}
+	return sb.String(), q.params
+}
+
+func (q *Query[T]) One(ctx context.Context, exe BasicExecutor) (T, error) {
+	if q.err != nil {
+		var zero T
+		return zero, q.err
+	}
+	query, params := q.sql(dialectOf(exe), nil)
+	return One[T](ctx, exe, query, params)
+}
+
+func (q *Query[T]) Many(ctx context.Context, exe BasicExecutor) ([]T, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	query, params := q.sql(dialectOf(exe), nil)
+	return Many[T](ctx, exe, query, params)
+}
+
+func (q *Query[T]) Iterate(ctx context.Context, exe BasicExecutor) (*Iterator[T], error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	query, params := q.sql(dialectOf(exe), nil)
+	return Iterate[T](ctx, exe, query, params)
+}
+
+func (q *Query[T]) Count(ctx context.Context, exe BasicExecutor) (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	return Scalar[int64](ctx, exe, "SELECT count(*) FROM "+q.repo.quotedTable(exe)+q.where(dialectOf(exe)), q.params)
+}
+
+// PageQuery returns a `PageQuery` of the conditions, for `Keyset` and `Offset`.
+// The table name is quoted by `DefaultDialect` if needed.
+func (q *Query[T]) PageQuery(keys []SortKey, limit int) PageQuery {
+	query, params := q.SQL()
+	return PageQuery{Query: query, Params: params, Keys: keys, Limit: limit}
+}
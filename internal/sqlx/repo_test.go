@@ -0,0 +1,131 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type _SoftUser struct {
+	Id        int64         `db:"id;incr;primary"`
+	Email     string        `db:"email;unique"`
+	DeletedAt sql.NullInt64 `db:"deleted_at;softdelete"`
+}
+
+func TestRepoQuery(t *testing.T) {
+	repo := NewRepo[_SoftUser]()
+	if !repo.SoftDelete() || repo.Table() != "_softuser" {
+		t.Fail()
+	}
+
+	q, params := repo.Query().Where("email = ${email}", Params{"email": "a@b.c"}).OrderBy("id DESC").Limit(10).SQL()
+	if q != "SELECT * FROM _softuser WHERE (email = ${email}) AND deleted_at IS NULL ORDER BY id DESC LIMIT 10" {
+		t.Fatal(q)
+	}
+	if params["email"] != "a@b.c" {
+		t.Fail()
+	}
+
+	q, _ = repo.Query().OnlyDeleted().SQL("id")
+	if q != "SELECT id FROM _softuser WHERE NOT (deleted_at IS NULL)" {
+		t.Fatal(q)
+	}
+	q, _ = repo.Query().WithDeleted().SQL()
+	if q != "SELECT * FROM _softuser" {
+		t.Fatal(q)
+	}
+}
+
+func TestQueryClone(t *testing.T) {
+	base := NewRepo[_SoftUser]().Query().Where("email = ${email}", Params{"email": "a@b.c"}).OrderBy("id DESC")
+	q, params := base.Clone().Where("id > ${id}", Params{"id": 1}).OrderByFirst("email").SQL()
+	if q != "SELECT * FROM _softuser WHERE (email = ${email}) AND (id > ${id}) AND deleted_at IS NULL ORDER BY email, id DESC" || len(params) != 2 {
+		t.Fatal(q, params)
+	}
+	q, params = base.SQL()
+	if q != "SELECT * FROM _softuser WHERE (email = ${email}) AND deleted_at IS NULL ORDER BY id DESC" || len(params) != 1 {
+		t.Fatal(q, params)
+	}
+}
+
+func TestSoftDeleteValue(t *testing.T) {
+	now := time.Now()
+	v, err := softDeleteValue(repoFieldType[_SoftUser]("deleted_at"), now)
+	if err != nil || v.Interface().(sql.NullInt64).Int64 != now.UnixMilli() {
+		t.Fail()
+	}
+	v, err = softDeleteValue(repoFieldType[struct{ V *time.Time }]("V"), now)
+	if err != nil || !v.Interface().(*time.Time).Equal(now) {
+		t.Fail()
+	}
+	if _, err = softDeleteValue(repoFieldType[struct{ V string }]("V"), now); err == nil {
+		t.Fail()
+	}
+
+	info := softDeleteField(reflect.TypeOf(struct {
+		V *time.Time `db:"v;softdelete"`
+	}{}))
+	if info == nil || aliveCondition(DefaultDialect{}.Quote, info) != "v IS NULL" {
+		t.Fatal(info)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("time.Time soft delete field is accepted")
+		}
+	}()
+	softDeleteField(reflect.TypeOf(struct {
+		V time.Time `db:"v;softdelete"`
+	}{}))
+}
+
+func repoFieldType[T any](name string) reflect.Type {
+	t, _ := structTypeOf[T]()
+	return DBReflectMapper.TypeMap(t).Names[name].Field.Type
+}
+
+type _HookedUser struct {
+	Name string `db:"name"`
+}
+
+func (u *_HookedUser) BeforeInsert(ctx context.Context) error {
+	if ExecutorFrom(ctx) == nil {
+		return errors.New("missing executor")
+	}
+	u.Name = strings.ToLower(u.Name)
+	return nil
+}
+
+func TestInvokeHook(t *testing.T) {
+	rows := []_HookedUser{{Name: "A"}, {Name: "B"}}
+	if err := InvokeHook(context.Background(), &DB{}, HookBeforeInsert, rows); err != nil {
+		t.Fatal(err)
+	}
+	user := &_HookedUser{Name: "C"}
+	if err := InvokeHook(context.Background(), &DB{}, HookBeforeInsert, user); err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Name != "a" || rows[1].Name != "b" || user.Name != "c" {
+		t.Fail()
+	}
+}
+
+type _SearchDoc struct {
+	Id     int64  `db:"id;incr;primary"`
+	Title  string `db:"title"`
+	Search string `db:"search;fts=title"`
+}
+
+func TestComputedField(t *testing.T) {
+	fields := insertFields(reflect.TypeOf(_SearchDoc{}), []reflect.Value{reflect.ValueOf(_SearchDoc{Search: "x"})})
+	if len(fields) != 1 || fields[0].Name != "title" {
+		t.Fatal(fields)
+	}
+	_, err := NewRepo[_SearchDoc]().update(context.Background(), nil, &_SearchDoc{}, []string{"search"})
+	if err == nil || !strings.Contains(err.Error(), "computed") {
+		t.Fatal(err)
+	}
+}
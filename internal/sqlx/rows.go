@@ -10,6 +10,22 @@ import (
 
 type Rows struct {
 	*sql.Rows
+	wrap func(ptr any) any
+}
+
+func (db *DB) newRows(rows *sql.Rows) *Rows {
+	r := &Rows{Rows: rows}
+	if w, ok := db.driver.(ScanWrapper); ok {
+		r.wrap = w.WrapScan
+	}
+	return r
+}
+
+func (rows *Rows) wrapScan(ptr any) any {
+	if rows.wrap == nil {
+		return ptr
+	}
+	return rows.wrap(ptr)
 }
 
 type DirectDist []interface{}
@@ -18,10 +34,7 @@ var (
 	directDistType        = reflect.TypeOf(DirectDist{})
 	interfaceType         = reflect.TypeOf((*interface{})(nil)).Elem()
 	ErrUnexpectedDistType = errors.New("0.0/internal/sqlx: unexpected dist type")
-	ErrEmptySlice         = errors.New(
-		"0.0/internal/sqlx: you should know how many rows will be fetched, please use a pre-allocated slice",
-	)
-	mapType = reflect.TypeOf(map[string]interface{}{})
+	mapType               = reflect.TypeOf(map[string]interface{}{})
 )
 
 func isStrAnyMapType(t reflect.Type) bool {
@@ -63,7 +76,7 @@ func (rows *Rows) doScan(dist interface{}, columns []string, temp *[]interface{}
 		}
 		return rows.scanToStruct(&v, columns, temp)
 	}
-	return rows.Rows.Scan(dist)
+	return rows.Rows.Scan(rows.wrapScan(dist))
 }
 
 // Scan
@@ -97,15 +110,48 @@ func (rows *Rows) scanToMap(v *reflect.Value, columns []string, temp *[]interfac
 func (rows *Rows) scanToStruct(v *reflect.Value, columns []string, temp *[]interface{}) error {
 	defer func() { *temp = (*temp)[:0] }()
 
+	if err := rows.structPtrs(v, columns, temp); err != nil {
+		return err
+	}
+	return rows.Rows.Scan(*temp...)
+}
+
+func (rows *Rows) structPtrs(v *reflect.Value, columns []string, temp *[]interface{}) error {
 	vm := DBReflectMapper.FieldMap(*v)
 	for _, c := range columns {
 		v, ok := vm[c]
 		if !ok {
 			return fmt.Errorf("0.0/internal/sqlx: missing column `%s`", c)
 		}
-		*temp = append(*temp, v.Addr().Interface())
+		*temp = append(*temp, rows.wrapScan(v.Addr().Interface()))
 	}
-	return rows.Rows.Scan(*temp...)
+	return nil
+}
+
+// ScanExtra scans the last `len(extra)` columns into `extra`, and the leading columns into the struct `dist`,
+// e.g. the rows of `SELECT t.*, rank ...`.
+func (rows *Rows) ScanExtra(dist interface{}, extra ...interface{}) error {
+	v := reflect.ValueOf(dist)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrUnexpectedDistType
+	}
+	v = v.Elem()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) < len(extra) {
+		return fmt.Errorf("0.0/internal/sqlx: %d columns, less than %d extra dists", len(columns), len(extra))
+	}
+	temp := make([]interface{}, 0, len(columns))
+	if err = rows.structPtrs(&v, columns[:len(columns)-len(extra)], &temp); err != nil {
+		return err
+	}
+	for _, ptr := range extra {
+		temp = append(temp, rows.wrapScan(ptr))
+	}
+	return rows.Rows.Scan(temp...)
 }
 
 func (rows *Rows) selectToPointerSlice(sliceV reflect.Value, et reflect.Type) (*reflect.Value, error) {
@@ -132,10 +178,6 @@ func (rows *Rows) selectToValueSlice(sliceV reflect.Value, et reflect.Type) (*re
 		return nil, err
 	}
 
-	if sliceV.Cap() < 1 {
-		return nil, ErrEmptySlice
-	}
-
 	var temp = make([]interface{}, 0, len(columns))
 
 	for rows.Next() {
@@ -220,7 +262,7 @@ func (rows *Rows) ScanJoined(dist interface{}, get JoinedEmbedDistGetter) error
 			return fmt.Errorf("0.0/internal/sqlx: bad column name `%s`", c)
 		}
 		f := cdV.FieldByIndex(fi.Index)
-		ptrs = append(ptrs, f.Addr().Interface())
+		ptrs = append(ptrs, rows.wrapScan(f.Addr().Interface()))
 		cdFieldsRemain--
 		if cdFieldsRemain < 1 {
 			cdT = nil
@@ -275,9 +317,6 @@ func (rows *Rows) selectJoined(slicePtr interface{}, joinedGet JoinedEmbedDistGe
 	sliceV := reflect.ValueOf(slicePtr).Elem()
 	eleT := sliceV.Type().Elem()
 	isPtrSlice := eleT.Kind() == reflect.Ptr
-	if !isPtrSlice && sliceV.Cap() < 1 {
-		return ErrEmptySlice
-	}
 
 	for rows.Next() {
 		var elePtrV reflect.Value
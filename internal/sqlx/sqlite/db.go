@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type DB struct {
+	*sqlx.DB
+}
+
+func isMemory(dsn string) bool {
+	return dsn == ":memory:" || strings.Contains(dsn, "mode=memory") || strings.HasPrefix(dsn, "file::memory:")
+}
+
+// Open opens a file or an in-memory database. Every connection to an in-memory database opens a new empty one,
+// so the pool of it is limited to one connection.
+func Open(dsn string, readonly bool, logger sqlx.Logger) *DB {
+	db, err := sqlx.OpenDB(&Driver{}, dsn, readonly, logger)
+	if err != nil {
+		panic(err)
+	}
+	if isMemory(dsn) {
+		db.Raw().SetMaxOpenConns(1)
+		db.Raw().SetMaxIdleConns(1)
+	}
+	return &DB{db}
+}
@@ -0,0 +1,192 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	"github.com/zzztttkkk/0.0/internal/sqlx/postgres"
+	"github.com/zzztttkkk/0.0/internal/utils"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (_ *Driver) DDL(info *utils.FieldInfo) *sqlx.FieldDefinition {
+	var fd = &sqlx.FieldDefinition{}
+
+	// sqlite has no full text search type, the column keeps the text of sources, so models shared with postgres work.
+	if fts, ok := info.Options["fts"]; ok {
+		fd.SqlType = "TEXT"
+		fd.Generated = ftsExpr(fts)
+		return fd
+	}
+
+	// an `INTEGER` primary key is an alias of the rowid, it is assigned automatically, so `incr` needs nothing else.
+	fd.SqlType = sqliteType(info.Name, info.Field.Type, info.Options, fd)
+	if dv, ok := info.Options["default"]; ok {
+		fd.Default = sqliteDefault(dv)
+	}
+	return fd
+}
+
+// ftsExpr joins the source columns of `fts=col:weight,...` by spaces, weights are ignored.
+func ftsExpr(v string) string {
+	var parts []string
+	for _, src := range strings.Split(v, ",") {
+		column := strings.TrimSpace(strings.SplitN(src, ":", 2)[0])
+		if len(column) < 1 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("coalesce(%s, '')", Dialect{}.Quote(column)))
+	}
+	if len(parts) < 1 {
+		panic(fmt.Errorf("0.0/internal/sqlx/sqlite: bad fts sources: `%s`", v))
+	}
+	return strings.Join(parts, " || ' ' || ")
+}
+
+// _PgDefaults are the sqlite expressions of postgres defaults, keyed by the lower case default without spaces.
+var _PgDefaults = map[string]string{
+	"uuid_generate_v4()":                     _UuidV4,
+	"gen_random_uuid()":                      _UuidV4,
+	"now()":                                  "CURRENT_TIMESTAMP",
+	"(extract(epochfromnow())*1000)::bigint": "(CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER))",
+}
+
+const _UuidV4 = "(lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || " +
+	"substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))))"
+
+// sqliteDefault translates the postgres functions used by defaults, others are kept as is.
+func sqliteDefault(v string) string {
+	if expr, ok := _PgDefaults[strings.ToLower(strings.Join(strings.Fields(v), ""))]; ok {
+		return expr
+	}
+	return v
+}
+
+func getLength(v string) int {
+	n, e := strconv.ParseUint(strings.TrimPrefix(v, "~"), 10, 32)
+	if e != nil {
+		panic(fmt.Errorf("bad field length: `%s`", v))
+	}
+	return int(n)
+}
+
+var (
+	_UuidType    = reflect.TypeOf((*pgtype.UUID)(nil)).Elem()
+	_DateType    = reflect.TypeOf((*pgtype.Date)(nil)).Elem()
+	_TimeType    = reflect.TypeOf((*time.Time)(nil)).Elem()
+	_HStoreType  = reflect.TypeOf((*pgtype.Hstore)(nil)).Elem()
+	_AnyJsonType = reflect.TypeOf((*postgres.AnyJSON)(nil)).Elem()
+	_NullTypes   = make(map[reflect.Type]reflect.Type)
+)
+
+func init() {
+	addToMap := func(a, b any) {
+		_NullTypes[reflect.TypeOf(a)] = reflect.TypeOf(b)
+	}
+	addToMap(sql.NullString{}, "")
+	addToMap(sql.NullBool{}, false)
+	addToMap(sql.NullFloat64{}, float64(0))
+	addToMap(sql.NullInt16{}, int16(0))
+	addToMap(sql.NullInt32{}, int32(0))
+	addToMap(sql.NullInt64{}, int64(0))
+	addToMap(sql.NullTime{}, time.Now())
+	addToMap(sql.NullByte{}, uint8(0))
+}
+
+// sqliteType maps the same go types as the postgres driver to SQLite type affinities,
+// ranges of small ints and lengths of strings are kept by checks.
+func sqliteType(name string, t reflect.Type, opts map[string]string, fd *sqlx.FieldDefinition) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Ptr {
+		panic(errors.New(`bad field type, always pointer`))
+	}
+
+	userType := strings.TrimSpace(opts["sqltype"])
+	if len(userType) > 0 {
+		return userType
+	}
+
+	switch t {
+	case _UuidType:
+		return "TEXT"
+	case _HStoreType, _AnyJsonType:
+		// stored as json
+		return "TEXT"
+	case _TimeType, _DateType:
+		return "DATETIME"
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "INTEGER"
+	case reflect.Int8:
+		fd.CheckAnd("%s < 128", name)
+		fd.CheckAnd("%s >= -128", name)
+		return "INTEGER"
+	case reflect.Int16:
+		fd.CheckAnd("%s < 32768", name)
+		fd.CheckAnd("%s >= -32768", name)
+		return "INTEGER"
+	case reflect.Int32:
+		fd.CheckAnd("%s < 2147483648", name)
+		fd.CheckAnd("%s >= -2147483648", name)
+		return "INTEGER"
+	case reflect.Uint, reflect.Uint64:
+		// values above the max int64 are stored as real
+		fd.CheckAnd("%s >= 0", name)
+		return "NUMERIC"
+	case reflect.Uint8:
+		fd.CheckAnd("%s < 256", name)
+		fd.CheckAnd("%s >= 0", name)
+		return "INTEGER"
+	case reflect.Uint16:
+		fd.CheckAnd("%s < 65536", name)
+		fd.CheckAnd("%s >= 0", name)
+		return "INTEGER"
+	case reflect.Uint32:
+		fd.CheckAnd("%s < 4294967296", name)
+		fd.CheckAnd("%s >= 0", name)
+		return "INTEGER"
+	case reflect.String:
+		{
+			if lengthOV := opts["length"]; len(lengthOV) > 0 {
+				if length := getLength(lengthOV); length > 0 {
+					fd.CheckAnd("length(%s) <= %d", name, length)
+				}
+			}
+			return "TEXT"
+		}
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	case reflect.Slice:
+		{
+			if t.Elem().Kind() == reflect.Uint8 {
+				return "BLOB"
+			}
+			// sqlite does not support arrays, stored as json
+			return "TEXT"
+		}
+	case reflect.Map:
+		return "TEXT"
+
+	case reflect.Struct:
+		{
+			realType := _NullTypes[t]
+			if realType != nil {
+				fd.Nullable = true
+				return sqliteType(name, realType, opts, fd)
+			}
+		}
+	}
+	panic(fmt.Errorf("unexpect field type, %s.%s", t.PkgPath(), t.Name()))
+}
@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+type _Account struct {
+	Id        int64          `db:"id;primary;incr"`
+	Email     string         `db:"email;length=~64;unique"`
+	Age       uint8          `db:"age"`
+	Avatar    []byte         `db:"avatar"`
+	Bio       sql.NullString `db:"bio"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func TestDDL(t *testing.T) {
+	ddl, _ := sqlx.TableDDL(&Driver{}, _Account{})
+	for _, v := range []string{
+		"id INTEGER NOT NULL,",
+		"email TEXT UNIQUE NOT NULL CHECK (length(email) <= 64),",
+		"age INTEGER NOT NULL CHECK (((age < 256) AND (age >= 0))),",
+		"avatar BLOB NOT NULL,",
+		"bio TEXT,",
+		"created_at DATETIME NOT NULL,",
+		"primary key (id)",
+	} {
+		if !strings.Contains(ddl, v) {
+			t.Fatalf("%q not in %s", v, ddl)
+		}
+	}
+}
+
+type _Member struct {
+	Id        int64          `db:"id;primary;incr"`
+	Email     string         `db:"email;length=~64;unique"`
+	Name      string         `db:"name"`
+	Group     string         `db:"group;default='none'"` // a reserved word
+	Bio       sql.NullString `db:"bio"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func (_Member) TableName() string { return "members" }
+
+func openMemory(t *testing.T) *sqlx.DB {
+	db := Open(":memory:", false, nil).DB
+	if err := db.CreateTable(context.Background(), _Member{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRepo(t *testing.T) {
+	db := openMemory(t)
+	ctx := context.Background()
+	members := sqlx.NewRepo[_Member]()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	m := &_Member{Email: "a@b.c", Name: "a", CreatedAt: now}
+	if err := members.Insert(ctx, db, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Id != 1 || m.Group != "none" {
+		t.Fatal(m)
+	}
+
+	got, err := members.Query().Where("email = ${email}", sqlx.Params{"email": "a@b.c"}).One(ctx, db)
+	if err != nil || got.Name != "a" || got.Bio.Valid || !got.CreatedAt.Equal(now) {
+		t.Fatal(got, err)
+	}
+
+	got.Name, got.Group = "b", "x"
+	if n, err := members.Update(ctx, db, &got, "name", "group"); err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	lst, err := members.Query().Where(`"group" = ${group}`, sqlx.Params{"group": "x"}).Many(ctx, db)
+	if err != nil || len(lst) != 1 || lst[0].Name != "b" {
+		t.Fatal(lst, err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db := openMemory(t)
+	ctx := context.Background()
+
+	m := &_Member{Email: "a@b.c", Name: "a", Group: "x", CreatedAt: time.Now()}
+	opts := &sqlx.UpsertOptions{UpdateColumns: []string{"name", "group"}, Returning: []string{"id", "group"}}
+	if _, err := db.Upsert(ctx, m, opts); err != nil {
+		t.Fatal(err)
+	}
+	m.Name, m.Group = "b", "y"
+	if _, err := db.Upsert(ctx, m, opts); err != nil {
+		t.Fatal(err)
+	}
+	lst, err := sqlx.Many[_Member](ctx, db, "SELECT * FROM members", nil)
+	if err != nil || len(lst) != 1 || lst[0].Name != "b" || lst[0].Group != "y" || lst[0].Id != m.Id {
+		t.Fatal(lst, err)
+	}
+}
+
+func TestBulkInsert(t *testing.T) {
+	db := openMemory(t)
+	ctx := context.Background()
+
+	// more params than the limit of old SQLite versions
+	rows := make([]_Member, 1000)
+	for i := range rows {
+		rows[i] = _Member{Email: fmt.Sprintf("%d@b.c", i), Name: "a", Group: "x", CreatedAt: time.Now()}
+	}
+	if n, err := db.BulkInsert(ctx, nil, rows); err != nil || n != 1000 {
+		t.Fatal(n, err)
+	}
+	if n, err := sqlx.Scalar[int64](ctx, db, `SELECT count(*) FROM members WHERE "group" = 'x'`, nil); err != nil || n != 1000 {
+		t.Fatal(n, err)
+	}
+}
+
+type _Profile struct {
+	Id     int64             `db:"id;primary;incr"`
+	Level  int8              `db:"level"`
+	Tags   []string          `db:"tags"`
+	Labels map[string]string `db:"labels;nullable"`
+	Uuid   string            `db:"uuid;default=gen_random_uuid()"`
+	Search string            `db:"search;fts=tags:A"`
+}
+
+func TestJSONTypes(t *testing.T) {
+	ddl, indexes := sqlx.TableDDL(&Driver{}, _Profile{})
+	for _, v := range []string{
+		"level INTEGER NOT NULL CHECK (((level < 128) AND (level >= -128))),",
+		"tags TEXT NOT NULL,",
+		"labels TEXT,",
+		"search TEXT GENERATED ALWAYS AS (coalesce(tags, '')) STORED NOT NULL,",
+		"DEFAULT " + _UuidV4,
+	} {
+		if !strings.Contains(ddl, v) {
+			t.Fatalf("%q not in %s", v, ddl)
+		}
+	}
+	// the gin index of the fts column is skipped
+	if len(indexes) != 0 {
+		t.Fatal(indexes)
+	}
+
+	db := Open(":memory:", false, nil).DB
+	ctx := context.Background()
+	if err := db.CreateTable(ctx, _Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	profiles := sqlx.NewRepo[_Profile]()
+	if err := profiles.Insert(ctx, db, &_Profile{Tags: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Insert(ctx, db, &_Profile{Tags: []string{}, Labels: map[string]string{"k": "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	lst, err := sqlx.Many[_Profile](ctx, db, "SELECT * FROM _profile WHERE id IN (${ids}) ORDER BY id", sqlx.Params{"ids": []int64{1, 2}})
+	if err != nil || len(lst) != 2 {
+		t.Fatal(lst, err)
+	}
+	if strings.Join(lst[0].Tags, ",") != "a,b" || lst[0].Labels != nil || lst[0].Search != `["a","b"]` || len(lst[0].Uuid) != 36 {
+		t.Fatal(lst[0])
+	}
+	if lst[1].Tags == nil || len(lst[1].Tags) != 0 || lst[1].Labels["k"] != "v" {
+		t.Fatal(lst[1])
+	}
+}
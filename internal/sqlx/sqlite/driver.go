@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+// Driver opens databases by the pure Go SQLite driver `modernc.org/sqlite`, which needs no cgo.
+type Driver struct {
+	// Name is the registered `database/sql` driver name, `sqlite`(modernc.org/sqlite) if empty.
+	// Another SQLite driver, e.g. `sqlite3`(mattn/go-sqlite3), can be used if it is registered.
+	Name string
+}
+
+// DefaultDriverName is the name registered by `modernc.org/sqlite`.
+const DefaultDriverName = "sqlite"
+
+func (d *Driver) driverName() string {
+	if len(d.Name) > 0 {
+		return d.Name
+	}
+	return DefaultDriverName
+}
+
+type _Connector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c *_Connector) Connect(_ context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+
+func (c *_Connector) Driver() driver.Driver { return c.drv }
+
+func (d *Driver) Open(dsn string) (driver.Connector, error) {
+	// sql.Open does not connect, it is only used to get the registered driver
+	db, err := sql.Open(d.driverName(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("0.0/internal/sqlx/sqlite: %w", err)
+	}
+	drv := db.Driver()
+	_ = db.Close()
+
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return &_Connector{dsn: dsn, drv: drv}, nil
+}
+
+func (_ *Driver) Placeholder(_ int, _ string) string { return "?" }
+
+// MaxParams is the default `SQLITE_MAX_VARIABLE_NUMBER`, which is 32766 since SQLite 3.32.0(bundled by
+// modernc.org/sqlite), and 999 before, other drivers may link an old version.
+func (d *Driver) MaxParams() int {
+	if d.driverName() == DefaultDriverName {
+		return 32766
+	}
+	return 999
+}
+
+var (
+	_ sqlx.Driver          = (*Driver)(nil)
+	_ sqlx.MaxParamsDriver = (*Driver)(nil)
+)
+
+func (_ *Driver) Dialect() sqlx.Dialect { return Dialect{} }
+
+type Dialect struct {
+	sqlx.DefaultDialect
+}
+
+// CreateIndex panics if the index uses a method or `INCLUDE`, which sqlite does not support, except `gin` indexes,
+// e.g. of `fts` columns, which are skipped.
+func (d Dialect) CreateIndex(tablename, name string, info *sqlx.IndexInfo) string {
+	if info.Using == "gin" && len(info.Include) < 1 {
+		return ""
+	}
+	if len(info.Using) > 0 || len(info.Include) > 0 {
+		panic(fmt.Errorf("0.0/internal/sqlx/sqlite: index `%s`, `USING` and `INCLUDE` are not supported", name))
+	}
+	return d.DefaultDialect.CreateIndex(tablename, name, info)
+}
+
+var _ sqlx.Dialect = Dialect{}
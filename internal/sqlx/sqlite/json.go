@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/zzztttkkk/0.0/internal/sqlx"
+)
+
+// Maps and slices(except `[]byte`), e.g. `pgtype.Hstore` and `[]string`, are stored as json text, the types postgres
+// stores as hstore and arrays. Their own `driver.Valuer` and `sql.Scanner` are not used, they use the postgres formats.
+
+func isJSONType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+func (_ *Driver) ConvertArg(arg any) (any, bool) {
+	if arg == nil || !isJSONType(reflect.TypeOf(arg)) {
+		return nil, false
+	}
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, true
+		}
+		v = v.Elem()
+	}
+	if v.IsNil() {
+		return nil, true
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		// kept, database/sql reports it as an unsupported arg
+		return nil, false
+	}
+	return string(data), true
+}
+
+type _JSONScanner struct {
+	ptr reflect.Value
+}
+
+func (s *_JSONScanner) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		dist := s.ptr.Elem()
+		dist.Set(reflect.Zero(dist.Type()))
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("0.0/internal/sqlx/sqlite: unexpected json source type %T", src)
+	}
+	return json.Unmarshal(data, s.ptr.Interface())
+}
+
+func (_ *Driver) WrapScan(ptr any) any {
+	pv := reflect.ValueOf(ptr)
+	if pv.Kind() != reflect.Ptr || !isJSONType(pv.Type().Elem()) {
+		return ptr
+	}
+	return &_JSONScanner{ptr: pv}
+}
+
+var (
+	_ sqlx.ArgConverter = (*Driver)(nil)
+	_ sqlx.ScanWrapper  = (*Driver)(nil)
+	_ sql.Scanner       = (*_JSONScanner)(nil)
+)
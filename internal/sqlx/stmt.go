@@ -8,6 +8,9 @@ import (
 type Stmt struct {
 	std    *sql.Stmt
 	keys   []string
+	query  string
+	db     *DB
+	tx     *Tx
 	logger Logger
 }
 
@@ -18,15 +21,23 @@ func (stmt *Stmt) Close() error {
 	return stmt.std.Close()
 }
 
+func (stmt *Stmt) event(kind QueryKind, args []interface{}) *QueryEvent {
+	return &QueryEvent{Kind: kind, Query: stmt.query, Args: args, Names: stmt.keys, Stmt: true, Tx: stmt.tx}
+}
+
 func (stmt *Stmt) Execute(ctx context.Context, params interface{}) (sql.Result, error) {
 	args, err := paramsToArgs(params, stmt.keys)
 	if err != nil {
 		return nil, err
 	}
+	args = convertArgs(stmt.db.driver, args)
 	if stmt.logger != nil {
-		stmt.logger.Printf("stmt execute, args(%v), tsql.Stmt(%p)", args, stmt.std)
+		stmt.logger.Printf("stmt execute, tsql.Stmt(%p)", stmt.std)
 	}
-	return stmt.std.ExecContext(ctx, args...)
+	ctx, event := stmt.db.beforeQuery(ctx, stmt.event(QueryExec, args))
+	result, err := stmt.std.ExecContext(ctx, args...)
+	stmt.db.afterQuery(ctx, event, result, err)
+	return result, err
 }
 
 func (stmt *Stmt) Rows(ctx context.Context, params interface{}) (*Rows, error) {
@@ -34,14 +45,17 @@ func (stmt *Stmt) Rows(ctx context.Context, params interface{}) (*Rows, error) {
 	if err != nil {
 		return nil, err
 	}
+	args = convertArgs(stmt.db.driver, args)
 	if stmt.logger != nil {
-		stmt.logger.Printf("stmt select, args(%v), tsql.Stmt(%p)", args, stmt.std)
+		stmt.logger.Printf("stmt select, tsql.Stmt(%p)", stmt.std)
 	}
+	ctx, event := stmt.db.beforeQuery(ctx, stmt.event(QueryRows, args))
 	rows, err := stmt.std.QueryContext(ctx, args...)
+	stmt.db.afterQuery(ctx, event, nil, err)
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{Rows: rows}, nil
+	return stmt.db.newRows(rows), nil
 }
 
 func (stmt *Stmt) FetchOne(ctx context.Context, params interface{}, dist interface{}) error {
@@ -0,0 +1,162 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/zzztttkkk/0.0/internal/utils"
+)
+
+type _CachedStmt struct {
+	std     *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+type _StmtCache struct {
+	sync.Mutex
+	db  *DB
+	lru *utils.LRUCache[string, *_CachedStmt]
+}
+
+// EnableStmtCache makes `Execute` and `Rows` of the db and its transactions run queries by prepared statements,
+// which are cached by the bound query text. At most `size` statements are kept, evicted ones are closed once
+// they are not in use. Queries without args are not cached. It should be called before the db is used.
+func (db *DB) EnableStmtCache(size int) *DB {
+	if size < 1 {
+		db.stmts = nil
+		return db
+	}
+	c := &_StmtCache{db: db}
+	c.lru = utils.NewLRUCache[string, *_CachedStmt](size, 0, nil).OnEvict(func(_ string, cs *_CachedStmt) {
+		cs.evicted = true
+		c.closeIfUnused(cs)
+	})
+	db.stmts = c
+	return db
+}
+
+// closeIfUnused must be called with the lock held.
+func (c *_StmtCache) closeIfUnused(cs *_CachedStmt) {
+	if cs.refs > 0 {
+		return
+	}
+	if err := cs.std.Close(); err != nil && c.db.logger != nil {
+		c.db.logger.Printf("0.0/internal/sqlx: close cached stmt error, %s", err)
+	}
+}
+
+// load returns the cached statement of `query` and holds it, it must be released.
+func (c *_StmtCache) load(query string) (*_CachedStmt, bool) {
+	c.Lock()
+	defer c.Unlock()
+	cs, ok := c.lru.Load(query)
+	if ok {
+		cs.refs++
+	}
+	return cs, ok
+}
+
+func (c *_StmtCache) acquire(ctx context.Context, query string) (*_CachedStmt, error) {
+	if cs, ok := c.load(query); ok {
+		return cs, nil
+	}
+
+	std, err := c.db.std.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if c.db.logger != nil {
+		c.db.logger.Printf("0.0/internal/sqlx: stmt cached: %s, Stmt(%p)", query, std)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if cs, ok := c.lru.Load(query); ok {
+		// prepared by another goroutine meanwhile
+		_ = std.Close()
+		cs.refs++
+		return cs, nil
+	}
+	cs := &_CachedStmt{std: std, refs: 1}
+	c.lru.Store(query, cs)
+	return cs, nil
+}
+
+func (c *_StmtCache) release(cs *_CachedStmt) {
+	c.Lock()
+	defer c.Unlock()
+	cs.refs--
+	if cs.evicted {
+		c.closeIfUnused(cs)
+	}
+}
+
+// cachedStmt returns the cached statement of `query`, rebound to `tx` if it is not nil.
+// The statements used by a transaction are kept by the top-level one and closed when it is done.
+// A transaction does not fill the cache: preparing by the db takes another connection, which deadlocks
+// when the pool is exhausted, e.g. a pool of one connection.
+func (db *DB) cachedStmt(ctx context.Context, tx *Tx, query string) (*sql.Stmt, func(), error) {
+	if tx == nil {
+		cs, err := db.stmts.acquire(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cs.std, func() { db.stmts.release(cs) }, nil
+	}
+
+	root := tx.root()
+	if stmt := root.stmts[query]; stmt != nil {
+		return stmt, func() {}, nil
+	}
+	var stmt *sql.Stmt
+	if cs, ok := db.stmts.load(query); ok {
+		// the transaction statement holds its parent until the transaction is done
+		stmt = tx.std.StmtContext(ctx, cs.std)
+		db.stmts.release(cs)
+	} else {
+		var err error
+		if stmt, err = tx.std.PrepareContext(ctx, query); err != nil {
+			return nil, nil, err
+		}
+	}
+	if root.stmts == nil {
+		root.stmts = make(map[string]*sql.Stmt)
+	}
+	root.stmts[query] = stmt
+	return stmt, func() {}, nil
+}
+
+func (db *DB) usesStmtCache(args []interface{}) bool { return db.stmts != nil && len(args) > 0 }
+
+func (db *DB) exec(ctx context.Context, tx *Tx, query string, args []interface{}) (sql.Result, error) {
+	if !db.usesStmtCache(args) {
+		if tx != nil {
+			return tx.std.ExecContext(ctx, query, args...)
+		}
+		return db.std.ExecContext(ctx, query, args...)
+	}
+	stmt, release, err := db.cachedStmt(ctx, tx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (db *DB) query(ctx context.Context, tx *Tx, query string, args []interface{}) (*sql.Rows, error) {
+	if !db.usesStmtCache(args) {
+		if tx != nil {
+			return tx.std.QueryContext(ctx, query, args...)
+		}
+		return db.std.QueryContext(ctx, query, args...)
+	}
+	stmt, release, err := db.cachedStmt(ctx, tx, query)
+	if err != nil {
+		return nil, err
+	}
+	// the rows hold the statement until they are closed
+	defer release()
+	return stmt.QueryContext(ctx, args...)
+}
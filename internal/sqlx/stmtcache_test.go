@@ -0,0 +1,146 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+type _FakeConn struct {
+	prepared map[string]int
+	closed   map[string]int
+	executed []string
+	results  map[string]*_FakeRows
+}
+
+func newFakeDB() (*DB, *_FakeConn) {
+	conn := &_FakeConn{prepared: map[string]int{}, closed: map[string]int{}, results: map[string]*_FakeRows{}}
+	std := sql.OpenDB(_FakeConnector{conn: conn})
+	std.SetMaxOpenConns(1)
+	return &DB{std: std, driver: _TestDriver{}}, conn
+}
+
+type _FakeStmt struct {
+	conn  *_FakeConn
+	query string
+}
+
+func (s *_FakeStmt) Close() error {
+	s.conn.closed[s.query]++
+	return nil
+}
+
+func (s *_FakeStmt) NumInput() int { return -1 }
+
+func (s *_FakeStmt) Exec(_ []driver.Value) (driver.Result, error) {
+	s.conn.executed = append(s.conn.executed, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *_FakeStmt) Query(_ []driver.Value) (driver.Rows, error) {
+	rows := s.conn.results[s.query]
+	if rows == nil {
+		return nil, errors.New("unsupported")
+	}
+	return &_FakeRows{columns: rows.columns, values: rows.values}, nil
+}
+
+// _FakeRows returns `values` as the result of a query, see `_FakeConn.results`.
+type _FakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	idx     int
+}
+
+func (r *_FakeRows) Columns() []string { return r.columns }
+
+func (r *_FakeRows) Close() error { return nil }
+
+func (r *_FakeRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.idx])
+	r.idx++
+	return nil
+}
+
+func (c *_FakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.prepared[query]++
+	return &_FakeStmt{conn: c, query: query}, nil
+}
+
+func (c *_FakeConn) Close() error { return nil }
+
+func (c *_FakeConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *_FakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) { return c, nil }
+
+func (c *_FakeConn) Commit() error { return nil }
+
+func (c *_FakeConn) Rollback() error { return nil }
+
+type _FakeConnector struct {
+	conn *_FakeConn
+}
+
+func (c _FakeConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+
+func (c _FakeConnector) Driver() driver.Driver { return nil }
+
+func TestStmtCache(t *testing.T) {
+	db, conn := newFakeDB()
+	db.EnableStmtCache(1)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.Execute(ctx, "a ${v}", Params{"v": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conn.prepared["a $1"] != 1 || conn.closed["a $1"] != 0 {
+		t.Fatal(conn.prepared, conn.closed)
+	}
+
+	if _, err := db.Execute(ctx, "b ${v}", Params{"v": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if conn.closed["a $1"] != 1 {
+		t.Fatal(conn.closed)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = tx.Execute(ctx, "b ${v}", Params{"v": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(tx.stmts) != 1 || conn.prepared["b $1"] != 1 {
+		t.Fatal(tx.stmts, conn.prepared)
+	}
+
+	// a miss in the transaction is prepared on its connection, the pool has only one
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if _, err = tx.Execute(tctx, "c ${v}", Params{"v": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(tx.stmts) != 2 || conn.prepared["c $1"] != 1 {
+		t.Fatal(tx.stmts, conn.prepared)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.stmts.lru.Load("c $1"); ok {
+		t.Fatal("stmt prepared by tx is cached")
+	}
+}
@@ -3,18 +3,35 @@ package sqlx
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 )
 
 type Tx struct {
 	std       *sql.Tx
+	conn      *sql.Conn // only set by `DB.BeginConnTx`
 	db        *DB
+	parent    *Tx
 	savepoint string
 	ctx       context.Context
 	readonly  bool
 	canceled  bool
+	done      bool
+
+	children    int  // count of opened nested transactions
+	seq         int  // savepoint name sequence, only used by the top-level transaction
+	aborted     bool // a nested transaction required to abort this one
+	abortParent bool
+
+	stmts map[string]*sql.Stmt // cached statements rebound to this transaction, only used by the top-level one
 }
 
+var (
+	ErrTxDone            = errors.New("0.0/internal/sqlx: tx is already committed or rolled back")
+	ErrTxHasOpenChildren = errors.New("0.0/internal/sqlx: tx has open nested transactions")
+	ErrTxAborted         = errors.New("0.0/internal/sqlx: tx is aborted by a nested transaction")
+)
+
 func (tx *Tx) Driver() Driver {
 	return tx.db.driver
 }
@@ -30,23 +47,28 @@ func (tx *Tx) BindParams(query string, params interface{}) (string, []interface{
 }
 
 func (tx *Tx) Execute(ctx context.Context, query string, params interface{}) (sql.Result, error) {
-	q, a, e := tx.BindParams(query, params)
+	q, a, n, e := tx.db.bind(query, params)
 	if e != nil {
 		return nil, e
 	}
-	return tx.std.ExecContext(ctx, q, a...)
+	ctx, event := tx.db.beforeQuery(ctx, &QueryEvent{Kind: QueryExec, Query: q, Args: a, Names: n, Stmt: tx.db.usesStmtCache(a), Tx: tx})
+	result, err := tx.db.exec(ctx, tx, q, a)
+	tx.db.afterQuery(ctx, event, result, err)
+	return result, err
 }
 
 func (tx *Tx) Rows(ctx context.Context, query string, params interface{}) (*Rows, error) {
-	q, a, e := tx.BindParams(query, params)
+	q, a, n, e := tx.db.bind(query, params)
 	if e != nil {
 		return nil, e
 	}
-	rows, err := tx.std.QueryContext(ctx, q, a...)
+	ctx, event := tx.db.beforeQuery(ctx, &QueryEvent{Kind: QueryRows, Query: q, Args: a, Names: n, Stmt: tx.db.usesStmtCache(a), Tx: tx})
+	rows, err := tx.db.query(ctx, tx, q, a)
+	tx.db.afterQuery(ctx, event, nil, err)
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{Rows: rows}, nil
+	return tx.db.newRows(rows), nil
 }
 
 func (tx *Tx) FetchOne(ctx context.Context, query string, params interface{}, dist interface{}) error {
@@ -65,9 +87,22 @@ func (tx *Tx) FetchManyJoined(ctx context.Context, query string, params interfac
 	return selectJoined(ctx, tx, query, params, dist, joinedGet)
 }
 
+func (tx *Tx) BulkInsert(ctx context.Context, tableOrModel any, rows any) (int64, error) {
+	return bulkInsert(ctx, tx, tableOrModel, rows)
+}
+
+func (tx *Tx) Upsert(ctx context.Context, model any, opts *UpsertOptions) (int64, error) {
+	return upsert(ctx, tx, model, opts)
+}
+
 func (tx *Tx) Prepare(ctx context.Context, query string) (*Stmt, error) {
+	if hasExpandParams(query) {
+		return nil, ErrExpandInStmt
+	}
 	query, keys := ScanParams(query, tx.db.driver)
-	stmt, err := tx.std.PrepareContext(ctx, query)
+	hctx, event := tx.db.beforeQuery(ctx, &QueryEvent{Kind: QueryPrepare, Query: query, Stmt: true, Tx: tx})
+	stmt, err := tx.std.PrepareContext(hctx, query)
+	tx.db.afterQuery(hctx, event, nil, err)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +112,9 @@ func (tx *Tx) Prepare(ctx context.Context, query string) (*Stmt, error) {
 	return &Stmt{
 		std:    stmt,
 		keys:   keys,
+		query:  query,
+		db:     tx.db,
+		tx:     tx,
 		logger: tx.db.logger,
 	}, nil
 }
@@ -86,72 +124,157 @@ func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
 	if tx.db.logger != nil {
 		tx.db.logger.Printf("0.0/internal/sqlx: tx wrap stmt: (%p)=>(%p), Tx(%p)", stmt, v, tx.std)
 	}
-	return &Stmt{std: v, keys: stmt.keys, logger: stmt.logger}
+	return &Stmt{std: v, keys: stmt.keys, query: stmt.query, db: tx.db, tx: tx, logger: stmt.logger}
 }
 
-var _ Executor = (*Tx)(nil)
+var _ BulkExecutor = (*Tx)(nil)
 
-func (tx *Tx) BeginTx(ctx context.Context, savepoint string) (*Tx, error) {
-	if tx.readonly {
-		return nil, ErrReadonly
+func (tx *Tx) root() *Tx {
+	for tx.parent != nil {
+		tx = tx.parent
 	}
-	if tx.db.logger != nil {
-		tx.db.logger.Printf("0.0/internal/sqlx: tx begin via savepoint, `%s`, Tx(%p);", savepoint, tx.std)
+	return tx
+}
+
+func (tx *Tx) isDone() bool {
+	for c := tx; c != nil; c = c.parent {
+		if c.done {
+			return true
+		}
 	}
+	return false
+}
 
-	_, err := tx.Execute(ctx, fmt.Sprintf("SAVEPOINT %s_BEGIN", savepoint), nil)
-	if err != nil {
+// BeginTx begins a nested transaction via savepoint, `opts` can be nil.
+func (tx *Tx) BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	if tx.isDone() {
+		return nil, ErrTxDone
+	}
+
+	child := &Tx{std: tx.std, conn: tx.conn, db: tx.db, parent: tx, ctx: ctx, readonly: tx.readonly}
+	if opts != nil {
+		if len(opts.Savepoint) > 0 {
+			child.savepoint = dialectOf(tx).Quote(opts.Savepoint)
+		}
+		child.readonly = child.readonly || opts.ReadOnly
+		child.abortParent = opts.AbortParentOnError
+	}
+	if len(child.savepoint) < 1 {
+		root := tx.root()
+		root.seq++
+		child.savepoint = fmt.Sprintf("sqlx_sp_%d", root.seq)
+	}
+
+	if tx.db.logger != nil {
+		tx.db.logger.Printf("0.0/internal/sqlx: tx begin via savepoint, `%s`, Tx(%p);", child.savepoint, tx.std)
+	}
+	if _, err := tx.Execute(ctx, fmt.Sprintf("SAVEPOINT %s", child.savepoint), nil); err != nil {
 		return nil, err
 	}
-	return &Tx{std: tx.std, db: tx.db, savepoint: savepoint, ctx: ctx}, nil
+	tx.children++
+	return child, nil
 }
 
-func (tx *Tx) MustBeginTx(ctx context.Context, savepoint string) *Tx {
-	t, e := tx.BeginTx(ctx, savepoint)
+func (tx *Tx) MustBeginTx(ctx context.Context, opts *TxOptions) *Tx {
+	t, e := tx.BeginTx(ctx, opts)
 	if e != nil {
 		panic(e)
 	}
 	return t
 }
 
+func (tx *Tx) closeConn() {
+	if tx.conn != nil {
+		_ = tx.conn.Close()
+	}
+}
+
+func (tx *Tx) finish(failed bool) {
+	tx.done = true
+	if tx.parent == nil {
+		return
+	}
+	tx.parent.children--
+	if failed && tx.abortParent {
+		tx.parent.aborted = true
+	}
+}
+
+// Commit commits the transaction, a canceled one is rolled back instead, see `Cancel`.
 func (tx *Tx) Commit() error {
 	if tx.canceled {
+		if !tx.isDone() {
+			// finishes the transaction and releases its connection
+			return tx.Rollback()
+		}
 		return nil
 	}
+	if tx.isDone() {
+		return ErrTxDone
+	}
+	if tx.children > 0 {
+		return ErrTxHasOpenChildren
+	}
+	if tx.aborted {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+		return ErrTxAborted
+	}
 
-	if len(tx.savepoint) < 1 {
+	if tx.parent == nil {
 		if tx.db.logger != nil {
 			tx.db.logger.Printf("0.0/internal/sqlx: tx commit, Tx(%p);", tx.std)
 		}
-		return tx.std.Commit()
+		// the transaction is finished whether the commit succeeds or not
+		tx.finish(false)
+		defer tx.closeConn()
+		ctx, event := tx.db.beforeQuery(tx.ctx, &QueryEvent{Kind: QueryTxCommit, Query: "COMMIT", Tx: tx})
+		err := tx.std.Commit()
+		tx.db.afterQuery(ctx, event, nil, err)
+		return err
 	}
 	if tx.db.logger != nil {
 		tx.db.logger.Printf("0.0/internal/sqlx: tx commit via savepoint, `%s`, Tx(%p);", tx.savepoint, tx.std)
 	}
-	_, err := tx.Execute(tx.ctx, fmt.Sprintf("SAVEPOINT %s", tx.savepoint), nil)
-	if err != nil {
+	if _, err := tx.Execute(tx.ctx, fmt.Sprintf("RELEASE SAVEPOINT %s", tx.savepoint), nil); err != nil {
 		return err
 	}
-	_, err = tx.Execute(tx.ctx, fmt.Sprintf("RELASE SAVEPOINT %s_BEGIN", tx.savepoint), nil)
-	return err
+	tx.finish(false)
+	return nil
 }
 
 func (tx *Tx) Rollback() error {
-	if len(tx.savepoint) < 1 {
+	if tx.isDone() {
+		return ErrTxDone
+	}
+
+	if tx.parent == nil {
 		if tx.db.logger != nil {
 			tx.db.logger.Printf("0.0/internal/sqlx: tx rollback, Tx(%p);", tx.std)
 		}
-		return tx.std.Rollback()
+		tx.finish(true)
+		defer tx.closeConn()
+		ctx, event := tx.db.beforeQuery(tx.ctx, &QueryEvent{Kind: QueryTxRollback, Query: "ROLLBACK", Tx: tx})
+		err := tx.std.Rollback()
+		tx.db.afterQuery(ctx, event, nil, err)
+		return err
 	}
 	if tx.db.logger != nil {
 		tx.db.logger.Printf("0.0/internal/sqlx: tx rollback via savepoint, `%s`, Tx(%p);", tx.savepoint, tx.std)
 	}
-	_, err := tx.Execute(tx.ctx, fmt.Sprintf("ROLLBACK TO SAVEPOINT %s_BEGIN", tx.savepoint), nil)
-	return err
+	if _, err := tx.Execute(tx.ctx, fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", tx.savepoint), nil); err != nil {
+		return err
+	}
+	if _, err := tx.Execute(tx.ctx, fmt.Sprintf("RELEASE SAVEPOINT %s", tx.savepoint), nil); err != nil {
+		return err
+	}
+	tx.finish(true)
+	return nil
 }
 
 func (tx *Tx) RollbackTo(savepoint string) error {
-	_, err := tx.Execute(tx.ctx, fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", savepoint), nil)
+	_, err := tx.Execute(tx.ctx, fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", dialectOf(tx).Quote(savepoint)), nil)
 	return err
 }
 
@@ -169,13 +292,16 @@ func (tx *Tx) AutoCommit() {
 			}
 		}
 	}
-	e = tx.Rollback()
-	if e != nil && tx.db.logger != nil {
-		tx.db.logger.Printf("0.0/internal/sqlx: rollback error: %s", e)
+	if !tx.done {
+		e = tx.Rollback()
+		if e != nil && tx.db.logger != nil {
+			tx.db.logger.Printf("0.0/internal/sqlx: rollback error: %s", e)
+		}
 	}
 	if v != nil {
 		panic(v)
 	}
 }
 
+// Cancel makes the transaction rolled back by `Commit` and `AutoCommit`.
 func (tx *Tx) Cancel() { tx.canceled = true }
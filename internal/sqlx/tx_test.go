@@ -0,0 +1,176 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNestedTx(t *testing.T) {
+	db, conn := newFakeDB()
+	ctx := context.Background()
+
+	tx := db.MustBeginTx(ctx, nil)
+	child := tx.MustBeginTx(ctx, nil)
+	grandchild := child.MustBeginTx(ctx, nil)
+	if err := child.Commit(); err != ErrTxHasOpenChildren {
+		t.Fatal(err)
+	}
+	if err := grandchild.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	another := tx.MustBeginTx(ctx, &TxOptions{Savepoint: "named"})
+	if err := another.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// names from users are quoted
+	another = tx.MustBeginTx(ctx, &TxOptions{Savepoint: `a"; DROP TABLE b; --`})
+	if err := another.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"SAVEPOINT sqlx_sp_1",
+		"SAVEPOINT sqlx_sp_2",
+		"RELEASE SAVEPOINT sqlx_sp_2",
+		"ROLLBACK TO SAVEPOINT sqlx_sp_1",
+		"RELEASE SAVEPOINT sqlx_sp_1",
+		"SAVEPOINT named",
+		"RELEASE SAVEPOINT named",
+		`SAVEPOINT "a""; DROP TABLE b; --"`,
+		`ROLLBACK TO SAVEPOINT "a""; DROP TABLE b; --"`,
+		`RELEASE SAVEPOINT "a""; DROP TABLE b; --"`,
+	}
+	if !reflect.DeepEqual(conn.executed, expected) {
+		t.Fatal(conn.executed)
+	}
+	if err := child.Commit(); err != ErrTxDone {
+		t.Fatal(err)
+	}
+}
+
+func TestNestedTxAbortParent(t *testing.T) {
+	db, conn := newFakeDB()
+	ctx := context.Background()
+
+	tx := db.MustBeginTx(ctx, nil)
+	child := tx.MustBeginTx(ctx, nil)
+	if err := child.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	// a rolled back child does not abort its parent by default
+	aborting := tx.MustBeginTx(ctx, &TxOptions{AbortParentOnError: true})
+	if err := aborting.Commit(); err != nil || tx.aborted {
+		t.Fatal(err)
+	}
+	aborting = tx.MustBeginTx(ctx, &TxOptions{AbortParentOnError: true})
+	if err := aborting.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != ErrTxAborted {
+		t.Fatal(err)
+	}
+	if !tx.done {
+		t.Fatal("aborted tx is not rolled back")
+	}
+	if err := tx.Rollback(); err != ErrTxDone {
+		t.Fatal(err)
+	}
+	if len(conn.executed) != 8 || conn.executed[7] != "RELEASE SAVEPOINT sqlx_sp_3" {
+		t.Fatal(conn.executed)
+	}
+}
+
+func TestNestedTxAfterParentDone(t *testing.T) {
+	db, conn := newFakeDB()
+	ctx := context.Background()
+
+	tx := db.MustBeginTx(ctx, nil)
+	child := tx.MustBeginTx(ctx, nil)
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Commit(); err != ErrTxDone {
+		t.Fatal(err)
+	}
+	if err := child.Rollback(); err != ErrTxDone {
+		t.Fatal(err)
+	}
+	if _, err := child.BeginTx(ctx, nil); err != ErrTxDone {
+		t.Fatal(err)
+	}
+	if len(conn.executed) != 1 {
+		t.Fatal(conn.executed)
+	}
+}
+
+func TestNestedTxReadonly(t *testing.T) {
+	db, _ := newFakeDB()
+	ctx := context.Background()
+
+	tx := db.MustBeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	child := tx.MustBeginTx(ctx, nil)
+	if !child.readonly {
+		t.Fatal("readonly is not inherited")
+	}
+	_ = child.Commit()
+	_ = tx.Commit()
+
+	tx = db.MustBeginTx(ctx, nil)
+	child = tx.MustBeginTx(ctx, &TxOptions{TxOptions: sql.TxOptions{ReadOnly: true}})
+	grandchild := child.MustBeginTx(ctx, nil)
+	if tx.readonly || !child.readonly || !grandchild.readonly {
+		t.Fatal(tx.readonly, child.readonly, grandchild.readonly)
+	}
+
+	db.readonly = true
+	if _, err := db.BeginTx(ctx, nil); err != ErrReadonly {
+		t.Fatal(err)
+	}
+}
+
+func TestTxRawConn(t *testing.T) {
+	db, conn := newFakeDB()
+	ctx := context.Background()
+
+	// a transaction does not pin a connection unless it is begun by `BeginConnTx`
+	tx := db.MustBeginTx(ctx, nil)
+	if err := RawConn(ctx, tx, func(any) error { return nil }); err != ErrNoRawConn {
+		t.Fatal(err)
+	}
+	_ = tx.Rollback()
+
+	for i := 0; i < 2; i++ {
+		// the pool has only one connection, it is released by the canceled commit
+		tctx, cancel := context.WithTimeout(ctx, time.Second)
+		tx, err := db.BeginConnTx(tctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = RawConn(ctx, tx, func(driverConn any) error {
+			if driverConn != conn {
+				t.Fatal(driverConn)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Cancel()
+		if err = tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if err = RawConn(ctx, tx, func(any) error { return nil }); err != sql.ErrConnDone {
+			t.Fatal(err)
+		}
+		cancel()
+	}
+}
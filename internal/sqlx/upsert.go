@@ -0,0 +1,197 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zzztttkkk/0.0/internal/utils"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type UpsertOptions struct {
+	// ConflictColumns is the conflict target, if empty, the first unique constraint(`primary`, `unique`,
+	// `unique=<group>` or an index named with suffix `unique`) whose columns are all inserted will be used.
+	// See `Dialect.Upsert`, mysql ignores it and handles the conflict of any unique index.
+	ConflictColumns []string
+	// DoNothing makes the conflicting row unchanged.
+	DoNothing bool
+	// UpdateColumns are the columns updated on conflict, all inserted columns except the conflict target if empty.
+	UpdateColumns []string
+	// Returning columns are scanned back into the model, which should be a pointer.
+	Returning []string
+}
+
+var ErrNoConflictTarget = errors.New("0.0/internal/sqlx: can not find the conflict target")
+
+// uniqueConstraints returns the column lists of unique constraints declared by the tags of `t`.
+func uniqueConstraints(t reflect.Type) [][]string {
+	var constraints [][]string
+	var primaryKeys []string
+	var uniques [][]string
+	var groups = make(map[string]int)
+	var indexes = make(map[string]*IndexInfo)
+
+	for _, info := range modelFields(t) {
+		if _, ok := info.Options["primary"]; ok {
+			primaryKeys = append(primaryKeys, info.Name)
+		}
+		if group, ok := info.Options["unique"]; ok {
+			if len(group) < 1 {
+				uniques = append(uniques, []string{info.Name})
+			} else if idx, exists := groups[group]; exists {
+				uniques[idx] = append(uniques[idx], info.Name)
+			} else {
+				groups[group] = len(uniques)
+				uniques = append(uniques, []string{info.Name})
+			}
+		}
+		for _, ief := range parseIndex(info.Name, info.Options["index"]) {
+			if strings.HasSuffix(ief.IndexName, "unique") {
+				appendIndex(indexes, ief)
+			}
+		}
+	}
+
+	if len(primaryKeys) > 0 {
+		constraints = append(constraints, primaryKeys)
+	}
+	constraints = append(constraints, uniques...)
+
+	names := utils.MapKeys(indexes)
+	sort.Strings(names)
+	for _, name := range names {
+		info := indexes[name]
+		sort.Slice(info.Fields, func(i, j int) bool { return info.Fields[i].SortInIndex < info.Fields[j].SortInIndex })
+		constraints = append(constraints, utils.SliceMap(info.Fields, func(_ int, f *IndexField) string { return f.FieldName }))
+	}
+	return constraints
+}
+
+func conflictTarget(t reflect.Type, columns []string) []string {
+	for _, cs := range uniqueConstraints(t) {
+		ok := true
+		for _, c := range cs {
+			if !utils.StrSliceContains(columns, c) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return cs
+		}
+	}
+	return nil
+}
+
+// Upsert inserts `model`, or updates the conflicting row by `opts`, see `UpsertOptions`.
+func Upsert(ctx context.Context, exe Executor, model any, opts *UpsertOptions) (int64, error) {
+	return upsert(ctx, exe, model, opts)
+}
+
+// upsert calls insert hooks, whether the row is inserted or updated.
+func upsert(ctx context.Context, exe Executor, model any, opts *UpsertOptions) (int64, error) {
+	if err := InvokeHook(ctx, exe, HookBeforeInsert, model); err != nil {
+		return 0, err
+	}
+	n, err := doUpsert(ctx, exe, model, opts)
+	if err != nil || n < 1 {
+		return n, err
+	}
+	return n, InvokeHook(ctx, exe, HookAfterInsert, model)
+}
+
+func doUpsert(ctx context.Context, exe Executor, model any, opts *UpsertOptions) (int64, error) {
+	if opts == nil {
+		opts = &UpsertOptions{}
+	}
+
+	mv, err := modelValue(model)
+	if err != nil {
+		return 0, err
+	}
+	if len(opts.Returning) > 0 && !dialectOf(exe).Returning() {
+		return 0, ErrReturningUnsupported
+	}
+	if len(opts.Returning) > 0 && reflect.ValueOf(model).Kind() != reflect.Ptr {
+		return 0, fmt.Errorf("0.0/internal/sqlx: `%T` is not a pointer, can not scan returning columns", model)
+	}
+
+	fields := insertFields(mv.Type(), []reflect.Value{mv})
+	if len(fields) < 1 {
+		return 0, fmt.Errorf("0.0/internal/sqlx: `%s` got empty columns", mv.Type())
+	}
+	columns := utils.SliceMap(fields, func(_ int, f *utils.FieldInfo) string { return f.Name })
+
+	target := opts.ConflictColumns
+	if len(target) < 1 {
+		target = conflictTarget(mv.Type(), columns)
+		if len(target) < 1 {
+			return 0, ErrNoConflictTarget
+		}
+	}
+
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(fields))
+	dialect := dialectOf(exe)
+	quote := dialect.Quote
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(quote(tableNameOf(mv)))
+	sb.WriteString(" (")
+	sb.WriteString(quoteJoin(quote, columns))
+	sb.WriteString(") VALUES (")
+	for i, info := range fields {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("${")
+		sb.WriteString(info.Name)
+		sb.WriteRune('}')
+		args = append(args, utils.FieldByIndexesReadOnly(mv, info.Index).Interface())
+	}
+	sb.WriteString(") ")
+
+	clause := &UpsertClause{Target: target}
+	if sf := softDeleteField(mv.Type()); sf != nil {
+		// infers the partial unique indexes created for soft delete models
+		clause.Where = aliveCondition(quote, sf)
+	}
+	version := versionField(mv.Type())
+	if !opts.DoNothing {
+		clause.Updates = opts.UpdateColumns
+		if len(clause.Updates) < 1 {
+			clause.Updates = utils.SliceFilter(columns, func(c string) bool { return !utils.StrSliceContains(target, c) })
+		}
+		if version != nil {
+			clause.Updates = utils.SliceFilter(clause.Updates, func(c string) bool { return c != version.Name })
+		}
+	}
+	if version != nil && len(clause.Updates) > 0 {
+		clause.Version = version.Name
+	}
+	sb.WriteString(dialect.Upsert(tableNameOf(mv), clause))
+
+	if len(opts.Returning) < 1 {
+		result, err := exe.Execute(ctx, sb.String(), args)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	sb.WriteString(" RETURNING ")
+	sb.WriteString(quoteJoin(quote, opts.Returning))
+	rows, err := exe.Rows(ctx, sb.String(), args)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	if err = rows.Scan(model); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
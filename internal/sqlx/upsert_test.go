@@ -0,0 +1,42 @@
+package sqlx
+
+import (
+	"reflect"
+	"testing"
+)
+
+type _UpsertUser struct {
+	Id    int64  `db:"id;incr;primary"`
+	Uuid  string `db:"uuid;unique;default=uuid_generate_v4()"`
+	Email string `db:"email;unique"`
+	Org   string `db:"org;index=org_name_unique,asc,1"`
+	Name  string `db:"name;index=org_name_unique,asc,0"`
+}
+
+func TestConflictTarget(t *testing.T) {
+	ut := reflect.TypeOf(_UpsertUser{})
+	if !reflect.DeepEqual(conflictTarget(ut, []string{"email", "org", "name"}), []string{"email"}) {
+		t.Fail()
+	}
+	if !reflect.DeepEqual(conflictTarget(ut, []string{"id", "email"}), []string{"id"}) {
+		t.Fail()
+	}
+	if !reflect.DeepEqual(conflictTarget(ut, []string{"org", "name"}), []string{"name", "org"}) {
+		t.Fail()
+	}
+	if conflictTarget(ut, []string{"org"}) != nil {
+		t.Fail()
+	}
+}
+
+func TestDefaultUpsert(t *testing.T) {
+	d := DefaultDialect{}
+	v := d.Upsert("user", &UpsertClause{Target: []string{"email"}, Updates: []string{"name", "group"}, Version: "version"})
+	if v != `ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, "group" = EXCLUDED."group", version = "user".version + 1` {
+		t.Fatal(v)
+	}
+	v = d.Upsert("user", &UpsertClause{Target: []string{"email"}, Where: "deleted_at = 0"})
+	if v != "ON CONFLICT (email) WHERE deleted_at = 0 DO NOTHING" {
+		t.Fatal(v)
+	}
+}
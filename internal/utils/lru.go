@@ -11,6 +11,7 @@ type LRUCache[K comparable, V any] struct {
 	maxSize int
 	maxAge  time.Duration
 	zero    V
+	onEvict func(K, V)
 }
 
 type _LRUCacheValue[K comparable, V any] struct {
@@ -28,6 +29,20 @@ func NewLRUCache[K comparable, V any](maxSize int, maxAge time.Duration, zero V)
 	}
 }
 
+// OnEvict sets the function called with entries removed by the size limit or the max age.
+func (lruc *LRUCache[K, V]) OnEvict(fn func(key K, value V)) *LRUCache[K, V] {
+	lruc.onEvict = fn
+	return lruc
+}
+
+func (lruc *LRUCache[K, V]) evict(ele *list.Element) {
+	lruc.delEle(ele)
+	if lruc.onEvict != nil {
+		cv := ele.Value.(*_LRUCacheValue[K, V])
+		lruc.onEvict(cv.key, cv.val)
+	}
+}
+
 func (lruc *LRUCache[K, V]) Size() int { return len(lruc.m) }
 
 func (lruc *LRUCache[K, V]) Store(key K, value V) {
@@ -48,8 +63,7 @@ func (lruc *LRUCache[K, V]) Store(key K, value V) {
 	}
 
 	for lruc.maxSize > 0 && len(lruc.m) > lruc.maxSize {
-		back := lruc.l.Back()
-		lruc.delEle(back)
+		lruc.evict(lruc.l.Back())
 	}
 }
 
@@ -74,8 +88,8 @@ func (lruc *LRUCache[K, V]) Load(key K) (V, bool) {
 	}
 
 	cv := ele.Value.(*_LRUCacheValue[K, V])
-	if lruc.maxAge > 0 && cv.endAt >= time.Now().UnixNano() {
-		lruc.delEle(ele)
+	if lruc.maxAge > 0 && cv.endAt < time.Now().UnixNano() {
+		lruc.evict(ele)
 		return lruc.zero, false
 	}
 	lruc.l.MoveToFront(ele)
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestLRUCacheSL(t *testing.T) {
@@ -24,3 +25,25 @@ func TestLRUCacheMaxSize(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestLRUCacheEvict(t *testing.T) {
+	var evicted []int
+	c := NewLRUCache[int, int](2, 0, 0).OnEvict(func(_ int, v int) { evicted = append(evicted, v) })
+	c.Store(1, 1)
+	c.Store(2, 2)
+	c.Load(1)
+	c.Store(3, 3)
+	if len(evicted) != 1 || evicted[0] != 2 {
+		t.Fail()
+	}
+
+	c = NewLRUCache[int, int](2, time.Millisecond, 0)
+	c.Store(1, 1)
+	if _, ok := c.Load(1); !ok {
+		t.Fail()
+	}
+	time.Sleep(time.Millisecond * 2)
+	if _, ok := c.Load(1); ok {
+		t.Fail()
+	}
+}